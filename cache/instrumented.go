@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xlog"
+)
+
+// InstrumentedOption is a function to configure *InstrumentedCache
+type InstrumentedOption func(*InstrumentedCache) *InstrumentedCache
+
+// ByKeyPrefix returns an InstrumentedOption to break down the key statistics by key prefixes.
+// If a key matches multiple prefixes, the longest one is used.
+func ByKeyPrefix(prefixes ...string) InstrumentedOption {
+	return func(c *InstrumentedCache) *InstrumentedCache {
+		for _, p := range prefixes {
+			c.prefixes[p] = &keyCounter{}
+		}
+		return c
+	}
+}
+
+// InstrumentedCache is a Cache wrapper to collect the statistics of the cache operations.
+type InstrumentedCache struct {
+	c        Cache
+	keys     keyCounter
+	ops      map[string]*opCounter
+	prefixes map[string]*keyCounter
+}
+
+// Operation names used in Stats.Ops
+const (
+	OpGetMulti    = "GetMulti"
+	OpSetMulti    = "SetMulti"
+	OpDeleteMulti = "DeleteMulti"
+	OpClear       = "Clear"
+)
+
+// Instrumented returns a new *InstrumentedCache on top of `c`
+func Instrumented(c Cache, options ...InstrumentedOption) *InstrumentedCache {
+	ic := &InstrumentedCache{
+		c: c,
+		ops: map[string]*opCounter{
+			OpGetMulti:    {},
+			OpSetMulti:    {},
+			OpDeleteMulti: {},
+			OpClear:       {},
+		},
+		prefixes: make(map[string]*keyCounter),
+	}
+	for _, f := range options {
+		ic = f(ic)
+	}
+	return ic
+}
+
+// GetMulti implements Cache#GetMulti.
+// Keys whose errors are ErrCacheKeyNotFound are counted as misses.
+func (ic *InstrumentedCache) GetMulti(ctx context.Context, keys []string, dst interface{}) error {
+	start := time.Now()
+	err := ic.c.GetMulti(ctx, keys, dst)
+	ic.ops[OpGetMulti].record(time.Now().Sub(start), err != nil && !isMissOnly(err))
+	var errors xerrors.MultiError
+	switch e := err.(type) {
+	case nil:
+		break
+	case xerrors.MultiError:
+		errors = e
+	default:
+		return err
+	}
+	for i, k := range keys {
+		var hit, miss, failed int64
+		switch {
+		case errors == nil || errors[i] == nil:
+			hit = 1
+		case isMiss(errors[i]):
+			miss = 1
+		default:
+			failed = 1
+		}
+		ic.forKey(k, func(kc *keyCounter) {
+			atomic.AddInt64(&kc.hits, hit)
+			atomic.AddInt64(&kc.misses, miss)
+			atomic.AddInt64(&kc.errors, failed)
+		})
+	}
+	return err
+}
+
+// SetMulti implements Cache#SetMulti
+func (ic *InstrumentedCache) SetMulti(ctx context.Context, keys []string, values interface{}) error {
+	start := time.Now()
+	err := ic.c.SetMulti(ctx, keys, values)
+	ic.ops[OpSetMulti].record(time.Now().Sub(start), err != nil)
+	if err == nil {
+		for _, k := range keys {
+			ic.forKey(k, func(kc *keyCounter) {
+				atomic.AddInt64(&kc.sets, 1)
+			})
+		}
+	}
+	return err
+}
+
+// DeleteMulti implements Cache#DeleteMulti
+func (ic *InstrumentedCache) DeleteMulti(ctx context.Context, keys []string) error {
+	start := time.Now()
+	err := ic.c.DeleteMulti(ctx, keys)
+	ic.ops[OpDeleteMulti].record(time.Now().Sub(start), err != nil)
+	if err == nil {
+		for _, k := range keys {
+			ic.forKey(k, func(kc *keyCounter) {
+				atomic.AddInt64(&kc.deletes, 1)
+			})
+		}
+	}
+	return err
+}
+
+// Clear implements Cache#Clear
+func (ic *InstrumentedCache) Clear(ctx context.Context) error {
+	start := time.Now()
+	err := ic.c.Clear(ctx)
+	ic.ops[OpClear].record(time.Now().Sub(start), err != nil)
+	return err
+}
+
+// InvalidateTags implements TagInvalidator#InvalidateTags by forwarding to the wrapped cache.
+func (ic *InstrumentedCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if inv, ok := ic.c.(TagInvalidator); ok {
+		return inv.InvalidateTags(ctx, tags...)
+	}
+	return ErrTagsNotSupported
+}
+
+func (ic *InstrumentedCache) supportsTags() bool {
+	return SupportsTags(ic.c)
+}
+
+// Stats returns the snapshot of the statistics
+func (ic *InstrumentedCache) Stats() *Stats {
+	s := &Stats{
+		KeyStats: ic.keys.snapshot(),
+		Ops:      make(map[string]*OpStats),
+		Prefixes: make(map[string]*KeyStats),
+	}
+	for name, oc := range ic.ops {
+		s.Ops[name] = oc.snapshot()
+	}
+	for p, kc := range ic.prefixes {
+		s.Prefixes[p] = kc.snapshot()
+	}
+	return s
+}
+
+// LogStats writes the current statistics to the logger.
+func (ic *InstrumentedCache) LogStats(ctx context.Context) {
+	_, logger := xlog.WithContextAndKey(ctx, "cache.stats", LoggerKey)
+	logger.Info(ic.Stats())
+}
+
+// ServeHTTP implements http.Handler to expose the statistics as JSON for debug endpoints.
+func (ic *InstrumentedCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(ic.Stats()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ic *InstrumentedCache) forKey(key string, f func(*keyCounter)) {
+	f(&ic.keys)
+	var matched string
+	var kc *keyCounter
+	for p, c := range ic.prefixes {
+		if strings.HasPrefix(key, p) && len(p) >= len(matched) {
+			matched = p
+			kc = c
+		}
+	}
+	if kc != nil {
+		f(kc)
+	}
+}
+
+func isMiss(err error) bool {
+	_, ok := err.(ErrCacheKeyNotFound)
+	return ok
+}
+
+// isMissOnly returns true if err is a MultiError that contains only ErrCacheKeyNotFound
+func isMissOnly(err error) bool {
+	errors, ok := err.(xerrors.MultiError)
+	if !ok {
+		return false
+	}
+	for _, e := range errors {
+		if e != nil && !isMiss(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Stats is a snapshot of the cache statistics
+type Stats struct {
+	*KeyStats
+	Ops      map[string]*OpStats  `json:"ops"`
+	Prefixes map[string]*KeyStats `json:"prefixes,omitempty"`
+}
+
+// KeyStats is a statistics by keys
+type KeyStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Sets    int64 `json:"sets"`
+	Deletes int64 `json:"deletes"`
+	Errors  int64 `json:"errors"`
+}
+
+// HitRatio returns the ratio of hits in the lookups
+func (s *KeyStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// OpStats is a statistics by operations
+type OpStats struct {
+	Calls        int64         `json:"calls"`
+	Errors       int64         `json:"errors"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+}
+
+// AvgLatency returns the average latency of the operation
+func (s *OpStats) AvgLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+type keyCounter struct {
+	hits    int64
+	misses  int64
+	sets    int64
+	deletes int64
+	errors  int64
+}
+
+func (kc *keyCounter) snapshot() *KeyStats {
+	return &KeyStats{
+		Hits:    atomic.LoadInt64(&kc.hits),
+		Misses:  atomic.LoadInt64(&kc.misses),
+		Sets:    atomic.LoadInt64(&kc.sets),
+		Deletes: atomic.LoadInt64(&kc.deletes),
+		Errors:  atomic.LoadInt64(&kc.errors),
+	}
+}
+
+type opCounter struct {
+	calls        int64
+	errors       int64
+	totalLatency int64
+	maxLatency   int64
+}
+
+func (oc *opCounter) record(latency time.Duration, failed bool) {
+	atomic.AddInt64(&oc.calls, 1)
+	if failed {
+		atomic.AddInt64(&oc.errors, 1)
+	}
+	atomic.AddInt64(&oc.totalLatency, int64(latency))
+	for {
+		max := atomic.LoadInt64(&oc.maxLatency)
+		if int64(latency) <= max || atomic.CompareAndSwapInt64(&oc.maxLatency, max, int64(latency)) {
+			break
+		}
+	}
+}
+
+func (oc *opCounter) snapshot() *OpStats {
+	return &OpStats{
+		Calls:        atomic.LoadInt64(&oc.calls),
+		Errors:       atomic.LoadInt64(&oc.errors),
+		TotalLatency: time.Duration(atomic.LoadInt64(&oc.totalLatency)),
+		MaxLatency:   time.Duration(atomic.LoadInt64(&oc.maxLatency)),
+	}
+}
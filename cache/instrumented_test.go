@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestInstrumented(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	ic := Instrumented(&MemoryCache{}, ByKeyPrefix("a.", "b."))
+	a.Nil(ic.SetMulti(ctx, []string{"a.1", "b.1"}, []*Example{{ID: "a1"}, {ID: "b1"}}))
+	dst := make([]*Example, 3)
+	a.NotNil(ic.GetMulti(ctx, []string{"a.1", "a.2", "b.1"}, dst))
+	a.Nil(ic.DeleteMulti(ctx, []string{"a.1"}))
+	a.NotNil(ic.GetMulti(ctx, []string{"a.1"}, make([]Example, 2)))
+
+	stats := ic.Stats()
+	a.EqInt64(2, stats.Hits)
+	a.EqInt64(1, stats.Misses)
+	a.EqInt64(2, stats.Sets)
+	a.EqInt64(1, stats.Deletes)
+	a.EqInt64(2, stats.Ops[OpGetMulti].Calls)
+	a.EqInt64(1, stats.Ops[OpGetMulti].Errors)
+	a.EqInt64(1, stats.Prefixes["a."].Hits)
+	a.EqInt64(1, stats.Prefixes["a."].Misses)
+	a.EqInt64(1, stats.Prefixes["b."].Hits)
+	a.EqFloat64(2.0/3.0, stats.HitRatio())
+
+	w := httptest.NewRecorder()
+	ic.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	var decoded Stats
+	a.Nil(json.Unmarshal(w.Body.Bytes(), &decoded))
+	a.EqInt64(2, decoded.Hits)
+	a.EqInt64(1, decoded.Prefixes["b."].Sets)
+}
@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xlog"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "cache"
+
+// Loader is a function to load the values for cache missing keys.
+// dst is a slice that has the same type as the one passed to GetOrLoad and the same length as keys.
+// The loader should leave dst[i] as a zero value if the value for keys[i] does not exist.
+type Loader func(ctx context.Context, keys []string, dst interface{}) error
+
+// LoadOption is a function to configure GetOrLoad
+type LoadOption func(*loadConfig) *loadConfig
+
+type loadConfig struct {
+	ttl  time.Duration
+	beta float64
+}
+
+// EarlyRefresh returns a LoadOption to refresh the values loaded by this process before `ttl` passes.
+// A cache hit is treated as a miss with the probability that grows as the expiration comes closer
+// and the time the loader took gets longer (a.k.a XFetch). `beta` > 1.0 favors earlier refreshes.
+// After `ttl` passes, the value is always refreshed.
+func EarlyRefresh(ttl time.Duration, beta float64) LoadOption {
+	return func(c *loadConfig) *loadConfig {
+		c.ttl = ttl
+		c.beta = beta
+		return c
+	}
+}
+
+// GetOrLoad gets the values for `keys` from the cache `c` into `dst` and calls `loader` for the cache missing keys.
+// The loader calls are deduplicated in the process so that concurrent callers for the same keys share one loader call.
+// The callers waiting for the other caller's loader get a shallow copy of the pointee for pointer element types,
+// so that they do not share the same pointer.
+// Loaded values are written back to the cache unless they are nil.
+// The returned error is xerrors.MultiError if loading some keys fails.
+func GetOrLoad(ctx context.Context, c Cache, keys []string, dst interface{}, loader Loader, options ...LoadOption) error {
+	config := &loadConfig{}
+	for _, f := range options {
+		config = f(config)
+	}
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+	if v.Len() != len(keys) {
+		return ErrInvalidDstLength
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	missing := make([]bool, len(keys))
+	switch errors := c.GetMulti(ctx, keys, dst).(type) {
+	case nil:
+		break
+	case xerrors.MultiError:
+		for i, e := range errors {
+			missing[i] = e != nil
+		}
+	default:
+		_, logger := xlog.WithContextAndKey(ctx, "cache", LoggerKey)
+		logger.Warnf("could not get values from the cache: %v", errors)
+		for i := range missing {
+			missing[i] = true
+		}
+	}
+
+	elemType := v.Type().Elem()
+	id := cacheIdentity(c)
+	var owned []int
+	var waits = make(map[int]*call)
+	defaultFlights.mu.Lock()
+	for i, k := range keys {
+		refresh := !missing[i] && config.ttl > 0 && defaultFlights.shouldRefresh(stampKey{cache: id, key: k}, config.beta)
+		if !missing[i] && !refresh {
+			continue
+		}
+		fk := flightKey{cache: id, key: k, typ: elemType}
+		if cl, ok := defaultFlights.m[fk]; ok {
+			if missing[i] {
+				waits[i] = cl
+			}
+			// a refresh is already in flight so use the current value.
+			continue
+		}
+		// the stamp is renewed when the value is loaded.
+		delete(defaultFlights.stamps, stampKey{cache: id, key: k})
+		cl := &call{}
+		cl.wg.Add(1)
+		defaultFlights.m[fk] = cl
+		waits[i] = cl
+		owned = append(owned, i)
+	}
+	defaultFlights.mu.Unlock()
+
+	if len(owned) > 0 {
+		load(ctx, c, id, keys, owned, waits, v.Type(), loader, config)
+	}
+
+	isOwned := make(map[int]bool)
+	for _, i := range owned {
+		isOwned[i] = true
+	}
+	errors := xerrors.NewMultiError(len(keys))
+	for i, cl := range waits {
+		cl.wg.Wait()
+		if cl.err != nil {
+			if !missing[i] {
+				// failed to refresh but the current value is still available.
+				continue
+			}
+			errors[i] = cl.err
+			continue
+		}
+		if isOwned[i] {
+			v.Index(i).Set(cl.val)
+		} else {
+			v.Index(i).Set(copyValue(cl.val))
+		}
+	}
+	return errors.ToReturn()
+}
+
+// copyValue returns a new pointer to the shallow copy of the pointee if `v` is a non nil pointer, or `v` itself.
+func copyValue(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return v
+	}
+	copied := reflect.New(v.Elem().Type())
+	copied.Elem().Set(v.Elem())
+	return copied
+}
+
+// load calls the loader for keys[owned] and resolves the corresponding calls.
+func load(ctx context.Context, c Cache, id interface{}, keys []string, owned []int, calls map[int]*call, sliceType reflect.Type, loader Loader, config *loadConfig) {
+	size := len(owned)
+	loadKeys := make([]string, size)
+	for i, idx := range owned {
+		loadKeys[i] = keys[idx]
+	}
+	loaded := reflect.MakeSlice(sliceType, size, size)
+	var err error
+	defer func() {
+		x := recover()
+		if x != nil {
+			err = xerrors.F("cache: loader panic: %v", x)
+		}
+		defaultFlights.mu.Lock()
+		for i, idx := range owned {
+			cl := calls[idx]
+			cl.err = err
+			if err == nil {
+				cl.val = loaded.Index(i)
+			}
+			delete(defaultFlights.m, flightKey{cache: id, key: loadKeys[i], typ: sliceType.Elem()})
+			cl.wg.Done()
+		}
+		defaultFlights.mu.Unlock()
+		if x != nil {
+			panic(x)
+		}
+	}()
+
+	start := xtime.Now()
+	if err = loader(ctx, loadKeys, loaded.Interface()); err != nil {
+		return
+	}
+	delta := xtime.Now().Sub(start)
+
+	var setKeys []string
+	var setIndexes []int
+	for i := 0; i < size; i++ {
+		if !isNilValue(loaded.Index(i)) {
+			setKeys = append(setKeys, loadKeys[i])
+			setIndexes = append(setIndexes, i)
+		}
+	}
+	if len(setKeys) == 0 {
+		return
+	}
+	values := reflect.MakeSlice(sliceType, len(setKeys), len(setKeys))
+	for i, idx := range setIndexes {
+		values.Index(i).Set(loaded.Index(idx))
+	}
+	if e := c.SetMulti(ctx, setKeys, values.Interface()); e != nil {
+		_, logger := xlog.WithContextAndKey(ctx, "cache", LoggerKey)
+		logger.Warnf("could not write back the loaded values to the cache: %v", e)
+		return
+	}
+	if config.ttl > 0 {
+		defaultFlights.storeStamps(id, setKeys, &stamp{expiry: start.Add(config.ttl), delta: delta})
+	}
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// flightKey identifies a loader call by the cache, the key and the value type.
+type flightKey struct {
+	cache interface{}
+	key   string
+	typ   reflect.Type
+}
+
+// stampKey identifies a stamp by the cache and the key.
+type stampKey struct {
+	cache interface{}
+	key   string
+}
+
+// cacheIdentity returns a map key to scope the flights and the stamps per Cache instance.
+// Caches that are not comparable (non-pointer values with maps or slices) are scoped by the type.
+func cacheIdentity(c Cache) interface{} {
+	if t := reflect.TypeOf(c); !t.Comparable() {
+		return t
+	}
+	return c
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val reflect.Value
+	err error
+}
+
+// stamp is a record when the value written by GetOrLoad expires
+type stamp struct {
+	expiry time.Time
+	delta  time.Duration
+}
+
+// minStampSweep is the number of stamps to start sweeping the expired ones.
+const minStampSweep = 1024
+
+type flights struct {
+	mu        sync.Mutex
+	m         map[flightKey]*call
+	stamps    map[stampKey]*stamp
+	nextSweep int
+}
+
+var defaultFlights = &flights{
+	m:         make(map[flightKey]*call),
+	stamps:    make(map[stampKey]*stamp),
+	nextSweep: minStampSweep,
+}
+
+// storeStamps stores the stamp for keys. The expired stamps are swept when the stamps grow
+// so that the stamps for the keys never read again do not remain.
+func (f *flights) storeStamps(id interface{}, keys []string, s *stamp) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range keys {
+		f.stamps[stampKey{cache: id, key: k}] = s
+	}
+	if len(f.stamps) < f.nextSweep {
+		return
+	}
+	now := xtime.Now()
+	for k, s := range f.stamps {
+		if !now.Before(s.expiry) {
+			delete(f.stamps, k)
+		}
+	}
+	f.nextSweep = 2 * len(f.stamps)
+	if f.nextSweep < minStampSweep {
+		f.nextSweep = minStampSweep
+	}
+}
+
+// randFloat64 can be replaced in tests for deterministic early refreshes.
+var randFloat64 = rand.Float64
+
+// shouldRefresh returns true if the value for the key should be refreshed. f.mu must be held.
+func (f *flights) shouldRefresh(key stampKey, beta float64) bool {
+	s, ok := f.stamps[key]
+	if !ok {
+		return false
+	}
+	now := xtime.Now()
+	if !now.Before(s.expiry) {
+		delete(f.stamps, key)
+		return true
+	}
+	if beta <= 0 {
+		beta = 1.0
+	}
+	gap := time.Duration(float64(s.delta) * beta * -math.Log(1-randFloat64()))
+	return !now.Add(gap).Before(s.expiry)
+}
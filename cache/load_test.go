@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("load and write back", func(t *testing.T) {
+		a := assert.New(t)
+		mc := &MemoryCache{}
+		a.Nil(mc.SetMulti(ctx, []string{"load.1"}, []*Example{{ID: "1"}}))
+		var loadedKeys []string
+		dst := make([]*Example, 3)
+		a.Nil(GetOrLoad(ctx, mc, []string{"load.1", "load.2", "load.3"}, dst, func(ctx context.Context, keys []string, dst interface{}) error {
+			loadedKeys = keys
+			dst.([]*Example)[0] = &Example{ID: "2"}
+			return nil
+		}))
+		a.EqInt(2, len(loadedKeys))
+		a.EqStr("1", dst[0].ID)
+		a.EqStr("2", dst[1].ID)
+		a.Nil(dst[2])
+
+		cached := make([]*Example, 1)
+		a.Nil(mc.GetMulti(ctx, []string{"load.2"}, cached))
+		a.EqStr("2", cached[0].ID)
+		// nil values are not written back
+		a.NotNil(mc.GetMulti(ctx, []string{"load.3"}, cached))
+	})
+
+	t.Run("loader error", func(t *testing.T) {
+		a := assert.New(t)
+		mc := &MemoryCache{}
+		dst := make([]*Example, 1)
+		err := GetOrLoad(ctx, mc, []string{"error.1"}, dst, func(ctx context.Context, keys []string, dst interface{}) error {
+			return fmt.Errorf("load error")
+		})
+		a.NotNil(err)
+		a.EqStr("load error", err.Error())
+	})
+
+	t.Run("deduplicate concurrent loads", func(t *testing.T) {
+		a := assert.New(t)
+		mc := &MemoryCache{}
+		var calls int32
+		var wg sync.WaitGroup
+		var start = make(chan struct{})
+		const n = 10
+		results := make([][]*Example, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				dst := make([]*Example, 1)
+				GetOrLoad(ctx, mc, []string{"dedup.1"}, dst, func(ctx context.Context, keys []string, dst interface{}) error {
+					atomic.AddInt32(&calls, 1)
+					time.Sleep(100 * time.Millisecond)
+					dst.([]*Example)[0] = &Example{ID: "1"}
+					return nil
+				})
+				results[i] = dst
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		a.EqInt(1, int(calls))
+		for i := 0; i < n; i++ {
+			a.EqStr("1", results[i][0].ID)
+		}
+	})
+
+	t.Run("distinct pointers for concurrent callers", func(t *testing.T) {
+		a := assert.New(t)
+		mc := &MemoryCache{}
+		started := make(chan struct{})
+		release := make(chan struct{})
+		loader := func(ctx context.Context, keys []string, dst interface{}) error {
+			close(started)
+			<-release
+			dst.([]*Example)[0] = &Example{ID: "1"}
+			return nil
+		}
+		owner := make([]*Example, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			a.Nil(GetOrLoad(ctx, mc, []string{"distinct.1"}, owner, loader))
+		}()
+		<-started
+		waiter := make([]*Example, 1)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+		a.Nil(GetOrLoad(ctx, mc, []string{"distinct.1"}, waiter, loader))
+		<-done
+		a.EqStr("1", owner[0].ID)
+		a.EqStr("1", waiter[0].ID)
+		a.OK(owner[0] != waiter[0])
+		waiter[0].ID = "mutated"
+		a.EqStr("1", owner[0].ID)
+	})
+
+	t.Run("early refresh", func(t *testing.T) {
+		a := assert.New(t)
+		mc := &MemoryCache{}
+		var calls int
+		loader := func(ctx context.Context, keys []string, dst interface{}) error {
+			calls++
+			dst.([]*Example)[0] = &Example{ID: fmt.Sprintf("%d", calls)}
+			return nil
+		}
+		base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+		option := EarlyRefresh(time.Minute, 1.0)
+		dst := make([]*Example, 1)
+		xtime.RunAt(base, func() {
+			a.Nil(GetOrLoad(ctx, mc, []string{"refresh.1"}, dst, loader, option))
+		})
+		a.EqStr("1", dst[0].ID)
+
+		randFloat64 = func() float64 { return 0 }
+		defer func() {
+			randFloat64 = origRandFloat64
+		}()
+		xtime.RunAt(base.Add(30*time.Second), func() {
+			a.Nil(GetOrLoad(ctx, mc, []string{"refresh.1"}, dst, loader, option))
+		})
+		a.EqStr("1", dst[0].ID)
+		xtime.RunAt(base.Add(time.Minute), func() {
+			a.Nil(GetOrLoad(ctx, mc, []string{"refresh.1"}, dst, loader, option))
+		})
+		a.EqStr("2", dst[0].ID)
+	})
+}
+
+var origRandFloat64 = randFloat64
+
+func TestGetOrLoad_scopedByCache(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	defaultFlights.mu.Lock()
+	defaultFlights.stamps = make(map[stampKey]*stamp)
+	defaultFlights.nextSweep = minStampSweep
+	defaultFlights.mu.Unlock()
+	mc1 := &MemoryCache{}
+	mc2 := &MemoryCache{}
+	var calls int
+	loader := func(ctx context.Context, keys []string, dst interface{}) error {
+		calls++
+		dst.([]*Example)[0] = &Example{ID: fmt.Sprintf("%d", calls)}
+		return nil
+	}
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	option := EarlyRefresh(time.Minute, 1.0)
+	dst := make([]*Example, 1)
+	xtime.RunAt(base, func() {
+		a.Nil(GetOrLoad(ctx, mc1, []string{"scoped.1"}, dst, loader, option))
+	})
+	// the stamp of mc1 does not expire the value in mc2
+	a.Nil(mc2.SetMulti(ctx, []string{"scoped.1"}, []*Example{{ID: "mc2"}}))
+	xtime.RunAt(base.Add(time.Minute), func() {
+		a.Nil(GetOrLoad(ctx, mc2, []string{"scoped.1"}, dst, loader, option))
+	})
+	a.EqStr("mc2", dst[0].ID)
+	a.EqInt(1, calls)
+
+	// expired stamps are swept
+	xtime.RunAt(base.Add(time.Hour), func() {
+		for i := 0; i < minStampSweep; i++ {
+			a.Nil(GetOrLoad(ctx, mc1, []string{fmt.Sprintf("sweep.%d", i)}, dst, loader, EarlyRefresh(time.Nanosecond, 1.0)))
+		}
+	})
+	defaultFlights.mu.Lock()
+	_, ok := defaultFlights.stamps[stampKey{cache: mc1, key: "scoped.1"}]
+	size := len(defaultFlights.stamps)
+	defaultFlights.mu.Unlock()
+	a.OK(!ok)
+	a.OK(size <= minStampSweep, size)
+}
@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+)
+
+// Tagger is a function to return the tags for the key.
+// It must return the same tags for the same key since tags are resolved on both reads and writes.
+type Tagger func(key string) []string
+
+// TaggedOption is a function to configure *Tagged
+type TaggedOption func(*Tagged) *Tagged
+
+// WithTagger returns a TaggedOption to set the Tagger
+func WithTagger(tagger Tagger) TaggedOption {
+	return func(t *Tagged) *Tagged {
+		t.tagger = tagger
+		return t
+	}
+}
+
+// Tagged is a Cache wrapper to invalidate entries by tags.
+//
+// Each tag has a generation stored in the underlying cache and an entry is stored under the key
+// combined with the generations of its tags. Invalidating a tag just updates its generation
+// so that the existing entries are never read again and no enumeration is needed.
+// The orphaned entries should be evicted by the underlying cache.
+type Tagged struct {
+	c      Cache
+	tagger Tagger
+	prefix string
+	tags   []string
+}
+
+// NewTagged returns a new *Tagged on top of `c`
+func NewTagged(c Cache, options ...TaggedOption) *Tagged {
+	t := &Tagged{
+		c: c,
+	}
+	for _, f := range options {
+		t = f(t)
+	}
+	return t
+}
+
+// NamespaceTag returns a tag name attached to the entries in the namespace view.
+func NamespaceTag(prefix string) string {
+	return fmt.Sprintf("namespace:%s", prefix)
+}
+
+// WithNamespace returns a view of the cache where keys are prefixed by `prefix`.
+// All entries in the view are tagged by NamespaceTag so Clear on the view invalidates only the namespace.
+func (t *Tagged) WithNamespace(prefix string) *Tagged {
+	fullPrefix := fmt.Sprintf("%s%s:", t.prefix, prefix)
+	tags := make([]string, len(t.tags), len(t.tags)+1)
+	copy(tags, t.tags)
+	return &Tagged{
+		c:      t.c,
+		tagger: t.tagger,
+		prefix: fullPrefix,
+		tags:   append(tags, NamespaceTag(fullPrefix)),
+	}
+}
+
+// GetMulti implements Cache#GetMulti
+func (t *Tagged) GetMulti(ctx context.Context, keys []string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+	if v.Len() != len(keys) {
+		return ErrInvalidDstLength
+	}
+	physicalKeys, err := t.resolve(ctx, keys, false)
+	if err != nil {
+		return err
+	}
+	var available []int
+	errors := xerrors.NewMultiError(len(keys))
+	for i, k := range physicalKeys {
+		if k == "" {
+			errors[i] = ErrCacheKeyNotFound(keys[i])
+		} else {
+			available = append(available, i)
+		}
+	}
+	size := len(available)
+	if size == 0 {
+		return errors
+	}
+	getKeys := make([]string, size)
+	getDst := reflect.MakeSlice(v.Type(), size, size)
+	for i, idx := range available {
+		getKeys[i] = physicalKeys[idx]
+	}
+	var getErrors xerrors.MultiError
+	switch e := t.c.GetMulti(ctx, getKeys, getDst.Interface()).(type) {
+	case nil:
+		break
+	case xerrors.MultiError:
+		getErrors = e
+	default:
+		return e
+	}
+	for i, idx := range available {
+		if getErrors != nil && getErrors[i] != nil {
+			if _, ok := getErrors[i].(ErrCacheKeyNotFound); ok {
+				errors[idx] = ErrCacheKeyNotFound(keys[idx])
+			} else {
+				errors[idx] = getErrors[i]
+			}
+			continue
+		}
+		v.Index(idx).Set(getDst.Index(i))
+	}
+	return errors.ToReturn()
+}
+
+// SetMulti implements Cache#SetMulti
+func (t *Tagged) SetMulti(ctx context.Context, keys []string, values interface{}) error {
+	physicalKeys, err := t.resolve(ctx, keys, true)
+	if err != nil {
+		return err
+	}
+	return t.c.SetMulti(ctx, physicalKeys, values)
+}
+
+// DeleteMulti implements Cache#DeleteMulti
+func (t *Tagged) DeleteMulti(ctx context.Context, keys []string) error {
+	physicalKeys, err := t.resolve(ctx, keys, false)
+	if err != nil {
+		return err
+	}
+	var deleteKeys []string
+	for _, k := range physicalKeys {
+		if k != "" {
+			deleteKeys = append(deleteKeys, k)
+		}
+	}
+	if len(deleteKeys) == 0 {
+		return nil
+	}
+	return t.c.DeleteMulti(ctx, deleteKeys)
+}
+
+// Clear implements Cache#Clear.
+// On a namespace view, only the entries in the namespace are invalidated.
+func (t *Tagged) Clear(ctx context.Context) error {
+	if len(t.tags) > 0 {
+		return t.Invalidate(ctx, t.tags[len(t.tags)-1])
+	}
+	return t.c.Clear(ctx)
+}
+
+// TagInvalidator is an interface for caches that can invalidate entries by tags.
+// *Tagged implements it and the wrappers such as *InstrumentedCache and *Tiered forward it to the wrapped caches.
+type TagInvalidator interface {
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// ErrTagsNotSupported is returned by InvalidateTags when no wrapped cache supports tags.
+var ErrTagsNotSupported = errors.New("cache: tags are not supported")
+
+// tagSupporter is implemented by the wrappers to tell SupportsTags whether the wrapped caches support tags.
+type tagSupporter interface {
+	supportsTags() bool
+}
+
+// SupportsTags returns true if `c` can invalidate entries by tags, directly or by the wrapped caches.
+func SupportsTags(c Cache) bool {
+	if s, ok := c.(tagSupporter); ok {
+		return s.supportsTags()
+	}
+	_, ok := c.(TagInvalidator)
+	return ok
+}
+
+// InvalidateTags implements TagInvalidator#InvalidateTags
+func (t *Tagged) InvalidateTags(ctx context.Context, tags ...string) error {
+	return t.Invalidate(ctx, tags...)
+}
+
+// Invalidate invalidates all entries tagged by any of `tags`
+func (t *Tagged) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	genKeys := make([]string, len(tags))
+	gens := make([]int64, len(tags))
+	for i, tag := range tags {
+		genKeys[i] = getGenerationKey(tag)
+		gens[i] = newGeneration()
+	}
+	return t.c.SetMulti(ctx, genKeys, gens)
+}
+
+// resolve returns the keys combined with the tag generations.
+// If `create` is false and a generation of the tag is not found, the key is resolved as an empty string.
+func (t *Tagged) resolve(ctx context.Context, keys []string, create bool) ([]string, error) {
+	keyTags := make([][]string, len(keys))
+	tagIndex := make(map[string]int)
+	var tags []string
+	for i, k := range keys {
+		keyTags[i] = t.tagsOf(k)
+		for _, tag := range keyTags[i] {
+			if _, ok := tagIndex[tag]; !ok {
+				tagIndex[tag] = len(tags)
+				tags = append(tags, tag)
+			}
+		}
+	}
+	gens := make([]int64, len(tags))
+	found := make([]bool, len(tags))
+	if len(tags) > 0 {
+		genKeys := make([]string, len(tags))
+		for i, tag := range tags {
+			genKeys[i] = getGenerationKey(tag)
+		}
+		switch e := t.c.GetMulti(ctx, genKeys, gens).(type) {
+		case nil:
+			for i := range found {
+				found[i] = true
+			}
+		case xerrors.MultiError:
+			for i := range found {
+				found[i] = e[i] == nil
+			}
+		default:
+			return nil, xerrors.Wrap(e, "could not get tag generations")
+		}
+		if create {
+			var newKeys []string
+			var newGens []int64
+			for i := range tags {
+				if !found[i] {
+					gens[i] = newGeneration()
+					found[i] = true
+					newKeys = append(newKeys, genKeys[i])
+					newGens = append(newGens, gens[i])
+				}
+			}
+			if len(newKeys) > 0 {
+				if err := t.c.SetMulti(ctx, newKeys, newGens); err != nil {
+					return nil, xerrors.Wrap(err, "could not set tag generations")
+				}
+			}
+		}
+	}
+	physicalKeys := make([]string, len(keys))
+	for i, k := range keys {
+		var buff strings.Builder
+		buff.WriteString(t.prefix)
+		buff.WriteString(k)
+		for j, tag := range keyTags[i] {
+			idx := tagIndex[tag]
+			if !found[idx] {
+				buff.Reset()
+				break
+			}
+			if j == 0 {
+				buff.WriteString("#")
+			} else {
+				buff.WriteString(".")
+			}
+			fmt.Fprintf(&buff, "%x", gens[idx])
+		}
+		physicalKeys[i] = buff.String()
+	}
+	return physicalKeys, nil
+}
+
+func (t *Tagged) tagsOf(key string) []string {
+	tags := make([]string, len(t.tags))
+	copy(tags, t.tags)
+	if t.tagger != nil {
+		tags = append(tags, t.tagger(key)...)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func getGenerationKey(tag string) string {
+	return fmt.Sprintf("cache.tag.%s", tag)
+}
+
+var generation struct {
+	sync.Mutex
+	last int64
+}
+
+// newGeneration returns a process-wide unique and increasing generation number.
+// It is based on the wall clock so that it does not conflict with ones generated by other instances.
+func newGeneration() int64 {
+	generation.Lock()
+	defer generation.Unlock()
+	gen := time.Now().UnixNano()
+	if gen <= generation.last {
+		gen = generation.last + 1
+	}
+	generation.last = gen
+	return gen
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestTagged(t *testing.T) {
+	ctx := context.Background()
+	tagger := Tagger(func(key string) []string {
+		return []string{strings.Split(key, ".")[0]}
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		a := assert.New(t)
+		tagged := NewTagged(&MemoryCache{}, WithTagger(tagger))
+		a.Nil(tagged.SetMulti(ctx, []string{"a.1", "b.1"}, []*Example{{ID: "a1"}, {ID: "b1"}}))
+		dst := make([]*Example, 2)
+		a.Nil(tagged.GetMulti(ctx, []string{"a.1", "b.1"}, dst))
+		a.EqStr("a1", dst[0].ID)
+		a.EqStr("b1", dst[1].ID)
+
+		a.Nil(tagged.Invalidate(ctx, "a"))
+		dst = make([]*Example, 2)
+		err := tagged.GetMulti(ctx, []string{"a.1", "b.1"}, dst)
+		a.NotNil(err)
+		_, ok := err.(ErrCacheKeyNotFound)
+		a.Not(ok)
+		a.Nil(dst[0])
+		a.EqStr("b1", dst[1].ID)
+
+		a.Nil(tagged.SetMulti(ctx, []string{"a.1"}, []*Example{{ID: "a1-2"}}))
+		a.Nil(tagged.GetMulti(ctx, []string{"a.1", "b.1"}, dst))
+		a.EqStr("a1-2", dst[0].ID)
+	})
+
+	t.Run("DeleteMulti", func(t *testing.T) {
+		a := assert.New(t)
+		tagged := NewTagged(&MemoryCache{}, WithTagger(tagger))
+		a.Nil(tagged.SetMulti(ctx, []string{"a.1"}, []*Example{{ID: "a1"}}))
+		a.Nil(tagged.DeleteMulti(ctx, []string{"a.1", "c.1"}))
+		dst := make([]*Example, 1)
+		a.NotNil(tagged.GetMulti(ctx, []string{"a.1"}, dst))
+	})
+
+	t.Run("WithNamespace", func(t *testing.T) {
+		a := assert.New(t)
+		tagged := NewTagged(&MemoryCache{})
+		ns1 := tagged.WithNamespace("tenant1")
+		ns2 := tagged.WithNamespace("tenant2")
+		a.Nil(ns1.SetMulti(ctx, []string{"1"}, []*Example{{ID: "tenant1"}}))
+		a.Nil(ns2.SetMulti(ctx, []string{"1"}, []*Example{{ID: "tenant2"}}))
+
+		dst := make([]*Example, 1)
+		a.Nil(ns1.GetMulti(ctx, []string{"1"}, dst))
+		a.EqStr("tenant1", dst[0].ID)
+		a.Nil(ns2.GetMulti(ctx, []string{"1"}, dst))
+		a.EqStr("tenant2", dst[0].ID)
+
+		a.Nil(ns1.Clear(ctx))
+		a.NotNil(ns1.GetMulti(ctx, []string{"1"}, dst))
+		a.Nil(ns2.GetMulti(ctx, []string{"1"}, dst))
+		a.EqStr("tenant2", dst[0].ID)
+	})
+}
+
+func TestTagged_wrapped(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	tagger := WithTagger(func(key string) []string {
+		return []string{"kind:" + key[:1]}
+	})
+	a.OK(!SupportsTags(&MemoryCache{}))
+	a.OK(!SupportsTags(Instrumented(&MemoryCache{})))
+	a.OK(!SupportsTags(NewTiered(&MemoryCache{}, &MemoryCache{})))
+
+	for name, c := range map[string]Cache{
+		"instrumented": Instrumented(NewTagged(&MemoryCache{}, tagger)),
+		"tiered":       NewTiered(&MemoryCache{}, NewTagged(&MemoryCache{}, tagger)),
+	} {
+		a.OK(SupportsTags(c), name)
+		a.Nil(c.SetMulti(ctx, []string{"a1", "b1"}, []*Example{{ID: "a1"}, {ID: "b1"}}), name)
+		a.Nil(c.(TagInvalidator).InvalidateTags(ctx, "kind:a"), name)
+		dst := make([]*Example, 2)
+		a.NotNil(c.GetMulti(ctx, []string{"a1", "b1"}, dst), name)
+		a.Nil(dst[0], name)
+		a.EqStr("b1", dst[1].ID, name)
+	}
+	a.OK(ErrTagsNotSupported == Instrumented(&MemoryCache{}).InvalidateTags(ctx, "kind:a"))
+}
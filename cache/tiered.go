@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xlog"
+)
+
+// Broadcaster is an interface to propagate L1 invalidations to the other instances.
+// keys is nil when the whole cache is cleared.
+// The receivers should call (*Tiered).Invalidate with the same keys.
+type Broadcaster interface {
+	Broadcast(ctx context.Context, keys []string) error
+}
+
+// BroadcasterFunc is a func to implement Broadcaster
+type BroadcasterFunc func(ctx context.Context, keys []string) error
+
+// Broadcast implements Broadcaster#Broadcast
+func (f BroadcasterFunc) Broadcast(ctx context.Context, keys []string) error {
+	return f(ctx, keys)
+}
+
+// TieredOption is a function to configure *Tiered
+type TieredOption func(*Tiered) *Tiered
+
+// WithBroadcaster returns a TieredOption to set the Broadcaster
+func WithBroadcaster(b Broadcaster) TieredOption {
+	return func(t *Tiered) *Tiered {
+		t.broadcaster = b
+		return t
+	}
+}
+
+// Tiered is a Cache implementation that composes a local cache (L1) in front of a shared cache (L2).
+type Tiered struct {
+	l1          Cache
+	l2          Cache
+	broadcaster Broadcaster
+}
+
+// NewTiered returns a new *Tiered for l1 and l2.
+func NewTiered(l1, l2 Cache, options ...TieredOption) *Tiered {
+	t := &Tiered{
+		l1: l1,
+		l2: l2,
+	}
+	for _, f := range options {
+		t = f(t)
+	}
+	return t
+}
+
+// GetMulti implements Cache#GetMulti.
+// The values missing in L1 are read from L2 and backfilled into L1.
+func (t *Tiered) GetMulti(ctx context.Context, keys []string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Slice {
+		return ErrInvalidDstType
+	}
+	if v.Len() != len(keys) {
+		return ErrInvalidDstLength
+	}
+	var missing []int
+	switch errors := t.l1.GetMulti(ctx, keys, dst).(type) {
+	case nil:
+		return nil
+	case xerrors.MultiError:
+		for i, e := range errors {
+			if e != nil {
+				missing = append(missing, i)
+			}
+		}
+	default:
+		for i := range keys {
+			missing = append(missing, i)
+		}
+	}
+
+	size := len(missing)
+	l2Keys := make([]string, size)
+	l2Dst := reflect.MakeSlice(v.Type(), size, size)
+	for i, idx := range missing {
+		l2Keys[i] = keys[idx]
+	}
+	errors := xerrors.NewMultiError(len(keys))
+	l2Errors := xerrors.NewMultiError(size)
+	switch e := t.l2.GetMulti(ctx, l2Keys, l2Dst.Interface()).(type) {
+	case nil:
+		break
+	case xerrors.MultiError:
+		l2Errors = e
+	default:
+		for i := range l2Errors {
+			l2Errors[i] = e
+		}
+	}
+	var backfillKeys []string
+	var backfillIndexes []int
+	for i, idx := range missing {
+		if l2Errors[i] != nil {
+			errors[idx] = l2Errors[i]
+			continue
+		}
+		v.Index(idx).Set(l2Dst.Index(i))
+		backfillKeys = append(backfillKeys, l2Keys[i])
+		backfillIndexes = append(backfillIndexes, i)
+	}
+	if len(backfillKeys) > 0 {
+		values := reflect.MakeSlice(v.Type(), len(backfillKeys), len(backfillKeys))
+		for i, idx := range backfillIndexes {
+			values.Index(i).Set(l2Dst.Index(idx))
+		}
+		if err := t.l1.SetMulti(ctx, backfillKeys, values.Interface()); err != nil {
+			_, logger := xlog.WithContextAndKey(ctx, "cache.tiered", LoggerKey)
+			logger.Warnf("could not backfill L1 cache: %v", err)
+		}
+	}
+	return errors.ToReturn()
+}
+
+// SetMulti implements Cache#SetMulti.
+// The values are written to L2 first then L1.
+func (t *Tiered) SetMulti(ctx context.Context, keys []string, values interface{}) error {
+	if err := t.l2.SetMulti(ctx, keys, values); err != nil {
+		// L1 must not hold the values that L2 does not have.
+		t.l1.DeleteMulti(ctx, keys)
+		return err
+	}
+	if err := t.l1.SetMulti(ctx, keys, values); err != nil {
+		return err
+	}
+	return t.broadcast(ctx, keys)
+}
+
+// DeleteMulti implements Cache#DeleteMulti
+func (t *Tiered) DeleteMulti(ctx context.Context, keys []string) error {
+	if err := t.l2.DeleteMulti(ctx, keys); err != nil {
+		return err
+	}
+	if err := t.l1.DeleteMulti(ctx, keys); err != nil {
+		return err
+	}
+	return t.broadcast(ctx, keys)
+}
+
+// Clear implements Cache#Clear
+func (t *Tiered) Clear(ctx context.Context) error {
+	if err := t.l2.Clear(ctx); err != nil {
+		return err
+	}
+	if err := t.l1.Clear(ctx); err != nil {
+		return err
+	}
+	return t.broadcast(ctx, nil)
+}
+
+// Invalidate removes the keys from L1 only. If keys is nil, L1 is cleared.
+// This should be called when receiving the invalidation broadcasted by the other instances.
+func (t *Tiered) Invalidate(ctx context.Context, keys []string) error {
+	if keys == nil {
+		return t.l1.Clear(ctx)
+	}
+	return t.l1.DeleteMulti(ctx, keys)
+}
+
+// InvalidateTags implements TagInvalidator#InvalidateTags by forwarding to the tiers supporting tags.
+// L1 is cleared if it does not support tags, and L1 of the other instances are cleared by the broadcast
+// since they may keep the tagged entries.
+func (t *Tiered) InvalidateTags(ctx context.Context, tags ...string) error {
+	if !t.supportsTags() {
+		return ErrTagsNotSupported
+	}
+	if SupportsTags(t.l2) {
+		if err := t.l2.(TagInvalidator).InvalidateTags(ctx, tags...); err != nil {
+			return err
+		}
+	}
+	if SupportsTags(t.l1) {
+		if err := t.l1.(TagInvalidator).InvalidateTags(ctx, tags...); err != nil {
+			return err
+		}
+	} else if err := t.l1.Clear(ctx); err != nil {
+		return err
+	}
+	return t.broadcast(ctx, nil)
+}
+
+func (t *Tiered) supportsTags() bool {
+	return SupportsTags(t.l1) || SupportsTags(t.l2)
+}
+
+func (t *Tiered) broadcast(ctx context.Context, keys []string) error {
+	if t.broadcaster == nil {
+		return nil
+	}
+	if err := t.broadcaster.Broadcast(ctx, keys); err != nil {
+		return xerrors.Wrap(err, "could not broadcast the L1 invalidation")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("backfill", func(t *testing.T) {
+		a := assert.New(t)
+		l1 := &MemoryCache{}
+		l2 := &MemoryCache{}
+		tiered := NewTiered(l1, l2)
+		a.Nil(l1.SetMulti(ctx, []string{"1"}, []*Example{{ID: "1"}}))
+		a.Nil(l2.SetMulti(ctx, []string{"2"}, []*Example{{ID: "2"}}))
+
+		dst := make([]*Example, 3)
+		err := tiered.GetMulti(ctx, []string{"1", "2", "3"}, dst)
+		a.NotNil(err)
+		a.EqStr("1", dst[0].ID)
+		a.EqStr("2", dst[1].ID)
+		a.Nil(dst[2])
+
+		cached := make([]*Example, 1)
+		a.Nil(l1.GetMulti(ctx, []string{"2"}, cached))
+		a.EqStr("2", cached[0].ID)
+	})
+
+	t.Run("write both tiers", func(t *testing.T) {
+		a := assert.New(t)
+		l1 := &MemoryCache{}
+		l2 := &MemoryCache{}
+		var invalidated [][]string
+		tiered := NewTiered(l1, l2, WithBroadcaster(BroadcasterFunc(func(ctx context.Context, keys []string) error {
+			invalidated = append(invalidated, keys)
+			return nil
+		})))
+		a.Nil(tiered.SetMulti(ctx, []string{"1"}, []*Example{{ID: "1"}}))
+		cached := make([]*Example, 1)
+		a.Nil(l1.GetMulti(ctx, []string{"1"}, cached))
+		a.Nil(l2.GetMulti(ctx, []string{"1"}, cached))
+
+		a.Nil(tiered.DeleteMulti(ctx, []string{"1"}))
+		a.NotNil(l1.GetMulti(ctx, []string{"1"}, cached))
+		a.NotNil(l2.GetMulti(ctx, []string{"1"}, cached))
+
+		a.Nil(tiered.Clear(ctx))
+		a.EqInt(3, len(invalidated))
+		a.EqStr("1", invalidated[0][0])
+		a.Nil(invalidated[2])
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		a := assert.New(t)
+		l1 := &MemoryCache{}
+		l2 := &MemoryCache{}
+		tiered := NewTiered(l1, l2)
+		a.Nil(tiered.SetMulti(ctx, []string{"1"}, []*Example{{ID: "1"}}))
+		a.Nil(tiered.Invalidate(ctx, []string{"1"}))
+		cached := make([]*Example, 1)
+		a.NotNil(l1.GetMulti(ctx, []string{"1"}, cached))
+		a.Nil(l2.GetMulti(ctx, []string{"1"}, cached))
+	})
+}
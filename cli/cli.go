@@ -0,0 +1,398 @@
+// Package cli provides a small framework to build command line applications with subcommands.
+//
+// The flags are defined by the struct fields with the same tags as keyvalue.Bind, so that the flag names
+// are the configuration keys and they can override the other configuration sources.
+//
+//     var opts struct {
+//         Key    string `config:"k" usage:"hmac key string" required:"true"`
+//         Unsign bool   `config:"u" usage:"unsign the key string"`
+//     }
+//     cli.Main(&cli.Command{
+//         Name:  "hmacsign",
+//         Usage: "sign or unsign a string by HMAC",
+//         Flags: &opts,
+//         Run: func(ctx context.Context, args []string) error {
+//             ...
+//         },
+//     })
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/keyvalue/config"
+)
+
+// UsageError is an error for invalid command line arguments.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// Command is a command or a subcommand.
+type Command struct {
+	// Name is the command name used in the command line.
+	Name string
+	// Usage is a short description shown in the help.
+	Usage string
+	// Description is a long description shown in the help of the command.
+	Description string
+	// ArgsUsage describes the positional arguments, e.g. "[dir...]".
+	ArgsUsage string
+	// Flags is a pointer to the struct to define the flags. See keyvalue.Bind for the supported tags and types.
+	// `usage` tag is used in the help.
+	Flags interface{}
+	// Sources are the configuration sources used when the flags are not specified, such as config.EnvVar.
+	Sources []keyvalue.Getter
+	// Commands are the subcommands.
+	Commands []*Command
+	// Run is called with the remaining arguments after the flags are bound.
+	// If nil, the help is shown.
+	Run func(ctx context.Context, args []string) error
+	// Output is the writer for the help. Default is the parent's or os.Stderr.
+	Output io.Writer
+}
+
+// Main executes the command by os.Args and exits with the status code.
+// If COMP_LINE environment variable is set, it prints the completion candidates instead
+// so that `complete -C <command> <command>` enables the shell completion on bash.
+func Main(c *Command) {
+	if line, ok := os.LookupEnv("COMP_LINE"); ok {
+		for _, candidate := range c.completeLine(line) {
+			fmt.Println(candidate)
+		}
+		return
+	}
+	if err := c.Execute(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintf(c.output(), "%s: %v\n", c.Name, err)
+		var ue *UsageError
+		if errors.As(err, &ue) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// Execute parses `args` and runs the command or the subcommand.
+func (c *Command) Execute(ctx context.Context, args []string) error {
+	return c.execute(ctx, args, nil)
+}
+
+// execute runs the command as a subcommand of `parents`. The parents are passed as an argument, not stored in
+// the command, so that the same *Command can be shared by multiple parents.
+func (c *Command) execute(ctx context.Context, args []string, parents []*Command) error {
+	fs := c.newFlagSet(parents)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &UsageError{err}
+	}
+	if err := c.bind(fs); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) > 0 {
+		if sub := c.find(rest[0]); sub != nil {
+			return sub.execute(ctx, rest[1:], append(parents[:len(parents):len(parents)], c))
+		}
+	}
+	if c.Run == nil {
+		c.printHelp(parents)
+		if len(rest) > 0 {
+			return &UsageError{fmt.Errorf("unknown command %q", rest[0])}
+		}
+		return nil
+	}
+	return c.Run(ctx, rest)
+}
+
+func (c *Command) bind(fs *flag.FlagSet) error {
+	if c.Flags == nil {
+		return nil
+	}
+	sources := append([]keyvalue.Getter{config.Flags(fs)}, c.Sources...)
+	if err := keyvalue.Bind(keyvalue.NewList(sources...), c.Flags); err != nil {
+		return &UsageError{err}
+	}
+	return nil
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *Command) fullName(parents []*Command) string {
+	var names []string
+	for _, p := range parents {
+		names = append(names, p.Name)
+	}
+	return strings.Join(append(names, c.Name), " ")
+}
+
+func (c *Command) output(parents ...*Command) io.Writer {
+	if c.Output != nil {
+		return c.Output
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if parents[i].Output != nil {
+			return parents[i].Output
+		}
+	}
+	return os.Stderr
+}
+
+// PrintHelp prints the help of the command.
+func (c *Command) PrintHelp() {
+	c.printHelp(nil)
+}
+
+func (c *Command) printHelp(parents []*Command) {
+	w := c.output(parents...)
+	usage := c.fullName(parents)
+	if c.Flags != nil {
+		usage += " [flags]"
+	}
+	if len(c.Commands) > 0 {
+		usage += " <command>"
+	}
+	if c.ArgsUsage != "" {
+		usage += " " + c.ArgsUsage
+	}
+	fmt.Fprintf(w, "Usage: %s\n", usage)
+	if c.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Description)
+	} else if c.Usage != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Usage)
+	}
+	if len(c.Commands) > 0 {
+		fmt.Fprintf(w, "\nCommands:\n")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, sub := range c.Commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Usage)
+		}
+		tw.Flush()
+	}
+	defs := c.flagDefs()
+	if len(defs) > 0 {
+		fmt.Fprintf(w, "\nFlags:\n")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, def := range defs {
+			name := "-" + def.name
+			if !def.isBool {
+				name += " " + def.typeName
+			}
+			usage := def.usage
+			if def.def != "" {
+				usage += fmt.Sprintf(" (default: %s)", def.def)
+			}
+			if def.required {
+				usage += " (required)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", name, strings.TrimSpace(usage))
+		}
+		tw.Flush()
+	}
+}
+
+// Complete returns the completion candidates for `args`. If `next` is true, the candidates are
+// for the next argument, otherwise for the last (partial) argument.
+func (c *Command) Complete(args []string, next bool) []string {
+	var prefix string
+	if !next && len(args) > 0 {
+		prefix = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	cmd := c
+	defs := cmd.flagDefs()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") {
+			name := strings.TrimLeft(arg, "-")
+			if def := findFlagDef(defs, name); def != nil && !def.isBool && !strings.Contains(name, "=") {
+				i++ // skip the value
+			}
+			continue
+		}
+		if sub := cmd.find(arg); sub != nil {
+			cmd = sub
+			defs = cmd.flagDefs()
+		}
+	}
+	var candidates []string
+	if strings.HasPrefix(prefix, "-") {
+		for _, def := range defs {
+			if strings.HasPrefix("-"+def.name, prefix) {
+				candidates = append(candidates, "-"+def.name)
+			}
+		}
+	} else {
+		for _, sub := range cmd.Commands {
+			if strings.HasPrefix(sub.Name, prefix) {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// completeLine returns the completion candidates for COMP_LINE, which starts with the command name.
+func (c *Command) completeLine(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	return c.Complete(fields[1:], strings.HasSuffix(line, " "))
+}
+
+func (c *Command) newFlagSet(parents []*Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.fullName(parents), flag.ContinueOnError)
+	fs.SetOutput(c.output(parents...))
+	fs.Usage = func() {
+		c.printHelp(parents)
+	}
+	for _, def := range c.flagDefs() {
+		fs.Var(&flagValue{isBool: def.isBool, isSlice: def.isSlice}, def.name, def.usage)
+	}
+	return fs
+}
+
+type flagDef struct {
+	name     string
+	usage    string
+	def      string
+	typeName string
+	required bool
+	isBool   bool
+	isSlice  bool
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	secretType = reflect.TypeOf(keyvalue.Secret{})
+)
+
+func (c *Command) flagDefs() []*flagDef {
+	if c.Flags == nil {
+		return nil
+	}
+	v := reflect.ValueOf(c.Flags)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var defs []*flagDef
+	collectFlagDefs(v.Elem().Type(), "", &defs)
+	return defs
+}
+
+// collectFlagDefs walks the struct in the same way as keyvalue.Bind.
+func collectFlagDefs(t reflect.Type, prefix string, defs *[]*flagDef) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType && field.Type != secretType {
+			p := prefix
+			if key != "" {
+				p = prefix + key + "."
+			}
+			collectFlagDefs(field.Type, p, defs)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		def := &flagDef{
+			name:     prefix + key,
+			usage:    field.Tag.Get("usage"),
+			def:      field.Tag.Get("default"),
+			typeName: field.Type.Kind().String(),
+			isBool:   field.Type.Kind() == reflect.Bool,
+			isSlice:  field.Type.Kind() == reflect.Slice,
+		}
+		if field.Type == timeType {
+			def.typeName = "time"
+		} else if field.Type == secretType {
+			def.typeName = "string"
+		} else if field.Type.String() == "time.Duration" {
+			def.typeName = "duration"
+		}
+		def.required = field.Tag.Get("required") == "true"
+		*defs = append(*defs, def)
+	}
+}
+
+func findFlagDef(defs []*flagDef, name string) *flagDef {
+	for _, def := range defs {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// flagValue is a flag.Value that keeps the raw strings and converted by keyvalue.Bind.
+type flagValue struct {
+	values  []string
+	isBool  bool
+	isSlice bool
+}
+
+func (f *flagValue) String() string {
+	return strings.Join(f.values, ",")
+}
+
+func (f *flagValue) Set(s string) error {
+	if f.isSlice {
+		f.values = append(f.values, s)
+	} else {
+		f.values = []string{s}
+	}
+	return nil
+}
+
+// Get implements flag.Getter#Get
+func (f *flagValue) Get() interface{} {
+	if f.isSlice {
+		return f.values
+	}
+	if len(f.values) == 0 {
+		return ""
+	}
+	return f.values[0]
+}
+
+// IsBoolFlag is used by the flag package to allow `-name` without a value for bool fields.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+type serveFlags struct {
+	Port    int      `config:"port" usage:"port to listen" default:"8080"`
+	Verbose bool     `config:"v" usage:"verbose output"`
+	Tags    []string `config:"tag" usage:"tags"`
+	DB      struct {
+		Host string `config:"host" usage:"database host" required:"true"`
+	} `config:"db"`
+}
+
+func newTestCommand(out *bytes.Buffer, flags *serveFlags, called *[]string) *Command {
+	return &Command{
+		Name:   "app",
+		Usage:  "test application",
+		Output: out,
+		Commands: []*Command{
+			{
+				Name:  "serve",
+				Usage: "start the server",
+				Flags: flags,
+				Sources: []keyvalue.Getter{
+					keyvalue.StringKeyMap(map[string]interface{}{
+						"db.host": "source.example.com",
+					}),
+				},
+				Run: func(ctx context.Context, args []string) error {
+					*called = args
+					return nil
+				},
+			},
+			{
+				Name:  "version",
+				Usage: "show the version",
+				Run: func(ctx context.Context, args []string) error {
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func TestCommand_Execute(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	var flags serveFlags
+	var called []string
+	cmd := newTestCommand(&out, &flags, &called)
+	a.Nil(cmd.Execute(context.Background(), []string{"serve", "-v", "-tag", "a", "-tag", "b", "-db.host", "flag.example.com", "arg"}))
+	a.EqInt(8080, flags.Port)
+	a.OK(flags.Verbose)
+	a.EqStr("a,b", strings.Join(flags.Tags, ","))
+	a.EqStr("flag.example.com", flags.DB.Host)
+	a.EqStr("arg", strings.Join(called, ","))
+
+	flags = serveFlags{}
+	a.Nil(cmd.Execute(context.Background(), []string{"serve", "-port", "80"}))
+	a.EqInt(80, flags.Port)
+	a.OK(!flags.Verbose)
+	a.EqStr("source.example.com", flags.DB.Host)
+}
+
+func TestCommand_Execute_usageError(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	var flags serveFlags
+	var called []string
+	cmd := newTestCommand(&out, &flags, &called)
+	var ue *UsageError
+	err := cmd.Execute(context.Background(), []string{"serve", "-port", "abc"})
+	a.OK(errors.As(err, &ue))
+	err = cmd.Execute(context.Background(), []string{"serve", "-unknown"})
+	a.OK(errors.As(err, &ue))
+	err = cmd.Execute(context.Background(), []string{"unknown"})
+	a.OK(errors.As(err, &ue))
+}
+
+func TestCommand_PrintHelp(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	var flags serveFlags
+	var called []string
+	cmd := newTestCommand(&out, &flags, &called)
+	a.Nil(cmd.Execute(context.Background(), nil))
+	a.OK(strings.Contains(out.String(), "Usage: app <command>"), out.String())
+	a.OK(strings.Contains(out.String(), "serve"), out.String())
+	a.OK(strings.Contains(out.String(), "start the server"), out.String())
+
+	out.Reset()
+	a.Nil(cmd.Execute(context.Background(), []string{"serve", "-h"}))
+	a.OK(strings.Contains(out.String(), "Usage: app serve [flags]"), out.String())
+	a.OK(strings.Contains(out.String(), "-port int"), out.String())
+	a.OK(strings.Contains(out.String(), "(default: 8080)"), out.String())
+	a.OK(strings.Contains(out.String(), "-db.host string"), out.String())
+	a.OK(strings.Contains(out.String(), "(required)"), out.String())
+	a.OK(nil == called)
+}
+
+func TestCommand_Complete(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	var flags serveFlags
+	var called []string
+	cmd := newTestCommand(&out, &flags, &called)
+	a.EqStr("serve,version", strings.Join(cmd.Complete(nil, true), ","))
+	a.EqStr("version", strings.Join(cmd.Complete([]string{"v"}, false), ","))
+	a.EqStr("-db.host,-port,-tag,-v", strings.Join(cmd.Complete([]string{"serve", "-"}, false), ","))
+	a.EqStr("-tag", strings.Join(cmd.Complete([]string{"serve", "-port", "80", "-t"}, false), ","))
+}
+
+func TestCommand_sharedSubcommand(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	version := &Command{
+		Name:  "version",
+		Flags: &struct{}{},
+	}
+	app1 := &Command{Name: "app1", Output: &out, Commands: []*Command{version}}
+	app2 := &Command{Name: "app2", Output: &out, Commands: []*Command{version}}
+	a.Nil(app1.Execute(context.Background(), []string{"version"}))
+	a.OK(strings.Contains(out.String(), "Usage: app1 version"), out.String())
+	out.Reset()
+	a.Nil(app2.Execute(context.Background(), []string{"version", "-h"}))
+	a.OK(strings.Contains(out.String(), "Usage: app2 version"), out.String())
+}
+
+func TestCommand_completeLine(t *testing.T) {
+	a := assert.New(t)
+	var out bytes.Buffer
+	var flags serveFlags
+	var called []string
+	cmd := newTestCommand(&out, &flags, &called)
+	a.EqInt(0, len(cmd.completeLine("")))
+	a.EqInt(0, len(cmd.completeLine("   ")))
+	a.EqStr("serve,version", strings.Join(cmd.completeLine("app "), ","))
+}
@@ -17,6 +17,11 @@ func (e *ErrHTTP) Error() string {
 	return fmt.Sprintf("HTTPError (status: %s)", e.Response.Status)
 }
 
+// Retryable returns true if the status code is 5xx so that retry.IsRetryable can classify it.
+func (e *ErrHTTP) Retryable() bool {
+	return e.Response.StatusCode >= 500
+}
+
 // httpFetcher is an implementation to fetch a content from an url.
 type httpFetcher struct {
 	url    string
@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"cloud.google.com/go/datastore"
 	"github.com/yssk22/go/cache"
-	"github.com/yssk22/go/iterator/slice"
 	"github.com/yssk22/go/x/xcontext"
 	"github.com/yssk22/go/x/xerrors"
 	"github.com/yssk22/go/x/xlog"
+	"github.com/yssk22/go/x/xtime"
 )
 
 // Client is a wrapper for datastore.Client
@@ -56,8 +57,9 @@ func (c *Client) Close() error {
 }
 
 type clientConfig struct {
-	Cache     cache.Cache
-	Namespace *string
+	Cache            cache.Cache
+	Namespace        *string
+	NegativeCacheTTL time.Duration
 }
 
 func newClientConfig(options ...Option) *clientConfig {
@@ -71,14 +73,30 @@ func newClientConfig(options ...Option) *clientConfig {
 // Option is a function to configure CRUD operation
 type Option func(*clientConfig) *clientConfig
 
-// Cache to set the cache storage
+// Cache to set the cache storage.
+// Any cache.Cache implementation can be used including the composition like cache.NewTiered(l1, l2).
+// The entities are tagged by kind automatically by wrapping `c` with NewTaggedCache unless `c` already supports tags
+// (see cache.SupportsTags), in which case `c` should be built by NewTaggedCache for DeleteAll to invalidate the kind.
 func Cache(c cache.Cache) Option {
 	return Option(func(opts *clientConfig) *clientConfig {
+		if c != nil && !cache.SupportsTags(c) {
+			c = NewTaggedCache(c)
+		}
 		opts.Cache = c
 		return opts
 	})
 }
 
+// NegativeCache to cache the entity absence for `ttl` so that GetMulti does not access datastore
+// for the keys known as missing. The absence is stored as a marker under GetNegativeCacheKey
+// and cleared by PutMulti. This option is effective only when Cache is configured.
+func NegativeCache(ttl time.Duration) Option {
+	return Option(func(opts *clientConfig) *clientConfig {
+		opts.NegativeCacheTTL = ttl
+		return opts
+	})
+}
+
 var datastoreLoggerKey = struct{}{}
 
 // CrudEntsLimit is a limit of the number of entities that can be handled in one put or delete transaction
@@ -91,8 +109,6 @@ var (
 
 // GetMulti is wrapper for google.golang.org/appengine/datastore.GetMulti
 func (c *Client) GetMulti(ctx context.Context, keys []*datastore.Key, entities interface{}, options ...Option) error {
-	var err error
-	var memKeys []string
 	size := len(keys)
 	if size == 0 {
 		return nil
@@ -100,48 +116,86 @@ func (c *Client) GetMulti(ctx context.Context, keys []*datastore.Key, entities i
 	if size > CrudEntsLimit {
 		return ErrTooManyEnts
 	}
-
-	if c.config.Cache != nil {
-		memKeys = make([]string, size, size)
-		for i := range keys {
-			memKeys[i] = GetCacheKey(keys[i])
-		}
-		err = c.config.Cache.GetMulti(ctx, memKeys, entities)
-		if err == nil {
-			return nil
-		}
+	if c.config.Cache == nil {
+		return c.getMulti(ctx, keys, entities)
+	}
+	memKeys := make([]string, size, size)
+	dsKeys := make(map[string]*datastore.Key)
+	for i := range keys {
+		memKeys[i] = GetCacheKey(keys[i])
+		dsKeys[memKeys[i]] = keys[i]
 	}
-	// TODO: only fetch entities[i] that is nil
-	cacheMissingKeys := make([]*datastore.Key, 0)
-	cacheMissingEnts := slice.Filter(entities, func(i int, v interface{}) bool {
-		if reflect.ValueOf(v).IsNil() {
-			cacheMissingKeys = append(cacheMissingKeys, keys[i])
-			return false
+	return cache.GetOrLoad(ctx, c.config.Cache, memKeys, entities, func(ctx context.Context, missingKeys []string, dst interface{}) error {
+		cacheMissingKeys := make([]*datastore.Key, len(missingKeys))
+		for i, k := range missingKeys {
+			cacheMissingKeys[i] = dsKeys[k]
 		}
-		return true
+		if c.config.NegativeCacheTTL > 0 {
+			return c.getMultiWithNegativeCache(ctx, cacheMissingKeys, dst)
+		}
+		return c.getMulti(ctx, cacheMissingKeys, dst)
 	})
+}
 
-	// we check if err is an datastore error not to return "no such entity" error.
-	if err = c.inner.GetMulti(ctx, cacheMissingKeys, cacheMissingEnts); IsDatastoreError(err) {
-		_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s.%s", keys[0].Namespace, keys[0].Kind), datastoreLoggerKey)
-		logger.Fatalf("database error: %v", err)
-		return err
+// negativeCacheMarker is a value stored in the cache to represent the absence of the entity.
+type negativeCacheMarker struct {
+	Expiry time.Time
+}
+
+// getMultiWithNegativeCache is like getMulti but skips the keys marked as missing
+// and marks the keys not found in datastore.
+func (c *Client) getMultiWithNegativeCache(ctx context.Context, keys []*datastore.Key, entities interface{}) error {
+	size := len(keys)
+	markerKeys := make([]string, size)
+	for i := range keys {
+		markerKeys[i] = GetNegativeCacheKey(keys[i])
+	}
+	markers := make([]*negativeCacheMarker, size)
+	var markerErrors xerrors.MultiError
+	switch err := c.config.Cache.GetMulti(ctx, markerKeys, markers).(type) {
+	case nil:
+		break
+	case xerrors.MultiError:
+		markerErrors = err
+	default:
+		markerErrors = xerrors.NewMultiError(size)
+		for i := range markerErrors {
+			markerErrors[i] = err
+		}
+	}
+	now := xtime.Now()
+	var fetchIndexes []int
+	var fetchKeys []*datastore.Key
+	for i := range keys {
+		if (markerErrors == nil || markerErrors[i] == nil) && markers[i] != nil && now.Before(markers[i].Expiry) {
+			continue
+		}
+		fetchIndexes = append(fetchIndexes, i)
+		fetchKeys = append(fetchKeys, keys[i])
+	}
+	if len(fetchKeys) == 0 {
+		return nil
 	}
-	var cacheKeyIndex = 0
-	var entsFromDatastore = reflect.ValueOf(cacheMissingEnts)
 	ents := reflect.ValueOf(entities)
-	for i := 0; i < size; i++ {
-		elm := ents.Index(i)
-		if elm.IsNil() {
-			vv := entsFromDatastore.Index(cacheKeyIndex)
-			if !vv.IsNil() {
-				elm.Set(vv)
-			}
-			cacheKeyIndex++
+	fetched := reflect.MakeSlice(ents.Type(), len(fetchKeys), len(fetchKeys))
+	if err := c.getMulti(ctx, fetchKeys, fetched.Interface()); err != nil {
+		return err
+	}
+	var newMarkerKeys []string
+	var newMarkers []*negativeCacheMarker
+	for i, idx := range fetchIndexes {
+		v := fetched.Index(i)
+		if v.IsNil() {
+			newMarkerKeys = append(newMarkerKeys, markerKeys[idx])
+			newMarkers = append(newMarkers, &negativeCacheMarker{
+				Expiry: now.Add(c.config.NegativeCacheTTL),
+			})
+			continue
 		}
+		ents.Index(idx).Set(v)
 	}
-	if c.config.Cache != nil {
-		if err := c.config.Cache.SetMulti(ctx, memKeys, entities); err != nil {
+	if len(newMarkerKeys) > 0 {
+		if err := c.config.Cache.SetMulti(ctx, newMarkerKeys, newMarkers); err != nil {
 			_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s.%s", keys[0].Namespace, keys[0].Kind), datastoreLoggerKey)
 			logger.Warnf("could not update the datastore cache: %v", err)
 		}
@@ -149,6 +203,32 @@ func (c *Client) GetMulti(ctx context.Context, keys []*datastore.Key, entities i
 	return nil
 }
 
+// getCacheKeysToInvalidate returns the cache keys to be deleted when the entities for `keys` are updated.
+func (c *Client) getCacheKeysToInvalidate(keys []*datastore.Key) []string {
+	memKeys := make([]string, 0, len(keys)*2)
+	for i := range keys {
+		memKeys = append(memKeys, GetCacheKey(keys[i]))
+	}
+	if c.config.NegativeCacheTTL > 0 {
+		for i := range keys {
+			memKeys = append(memKeys, GetNegativeCacheKey(keys[i]))
+		}
+	}
+	return memKeys
+}
+
+// getMulti gets entities from datastore without caches.
+// ErrNoSuchEntity is not returned as an error and the corresponding entity is left as nil.
+func (c *Client) getMulti(ctx context.Context, keys []*datastore.Key, entities interface{}) error {
+	// we check if err is an datastore error not to return "no such entity" error.
+	if err := c.inner.GetMulti(ctx, keys, entities); IsDatastoreError(err) {
+		_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s.%s", keys[0].Namespace, keys[0].Kind), datastoreLoggerKey)
+		logger.Fatalf("database error: %v", err)
+		return err
+	}
+	return nil
+}
+
 // PutMulti is wrapper for google.golang.org/appengine/datastore.PutMulti
 func (c *Client) PutMulti(ctx context.Context, keys []*datastore.Key, ent interface{}, options ...Option) ([]*datastore.Key, error) {
 	var err error
@@ -167,10 +247,7 @@ func (c *Client) PutMulti(ctx context.Context, keys []*datastore.Key, ent interf
 	}
 
 	if c.config.Cache != nil {
-		memKeys := make([]string, size, size)
-		for i := range memKeys {
-			memKeys[i] = GetCacheKey(keys[i])
-		}
+		memKeys := c.getCacheKeysToInvalidate(keys)
 		if err = c.config.Cache.DeleteMulti(ctx, memKeys); err != nil {
 			_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s.%s", keys[0].Namespace, keys[0].Kind), datastoreLoggerKey)
 			logger.Warnf("could not update the datastore cache: %v", err)
@@ -197,10 +274,7 @@ func (c *Client) DeleteMulti(ctx context.Context, keys []*datastore.Key, options
 	}
 
 	if c.config.Cache != nil {
-		memKeys := make([]string, size, size)
-		for i := range memKeys {
-			memKeys[i] = GetCacheKey(keys[i])
-		}
+		memKeys := c.getCacheKeysToInvalidate(keys)
 		if err = c.config.Cache.DeleteMulti(ctx, memKeys); err != nil {
 			_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s.%s", keys[0].Namespace, keys[0].Kind), datastoreLoggerKey)
 			logger.Warnf("could not update the datastore cache: %v", err)
@@ -245,5 +319,11 @@ func (c *Client) DeleteAll(ctx context.Context, kind string) error {
 			break
 		}
 	}
+	if inv, ok := c.config.Cache.(cache.TagInvalidator); ok {
+		if err := inv.InvalidateTags(ctx, KindCacheTag(kind)); err != nil {
+			_, logger := xlog.WithContextAndKey(ctx, fmt.Sprintf("datastore.%s", kind), datastoreLoggerKey)
+			logger.Warnf("could not update the datastore cache: %v", err)
+		}
+	}
 	return nil
 }
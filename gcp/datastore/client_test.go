@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/datastore"
 	"github.com/yssk22/go/x/xtesting/assert"
@@ -70,6 +71,35 @@ func TestClient(t *testing.T) {
 		a.EqStr("example-a", stored[0].ID)
 	})
 
+	t.Run("NegativeCache", func(t *testing.T) {
+		a := assert.New(t)
+		a.Nil(testEnv.Reset())
+		nc := NewClientFromClient(ctx, c.inner, Cache(testEnv.memcache), NegativeCache(time.Minute))
+		keys := []*datastore.Key{
+			NewKey("Example", "example-a"),
+		}
+		stored := make([]*Example, 1, 1)
+		a.Nil(nc.GetMulti(ctx, keys, stored))
+		a.Nil(stored[0])
+
+		markers := make([]*negativeCacheMarker, 1, 1)
+		a.Nil(testEnv.memcache.GetMulti(ctx, []string{GetNegativeCacheKey(keys[0])}, markers))
+		a.NotNil(markers[0])
+
+		_, err := nc.PutMulti(ctx, keys, []*Example{
+			{
+				ID: "example-a",
+			},
+		})
+		a.Nil(err)
+		a.NotNil(testEnv.memcache.GetMulti(ctx, []string{GetNegativeCacheKey(keys[0])}, markers))
+
+		stored = make([]*Example, 1, 1)
+		a.Nil(nc.GetMulti(ctx, keys, stored))
+		a.NotNil(stored[0])
+		a.EqStr("example-a", stored[0].ID)
+	})
+
 	t.Run("DeleteMulti", func(t *testing.T) {
 		a := assert.New(t)
 		a.Nil(testEnv.Reset())
@@ -2,8 +2,10 @@ package datastore
 
 import (
 	"fmt"
+	"strings"
 
 	"cloud.google.com/go/datastore"
+	"github.com/yssk22/go/cache"
 )
 
 // LoggerKey is a key for logger in this package
@@ -32,6 +34,38 @@ func GetCacheKey(k *datastore.Key) string {
 	return fmt.Sprintf("datastore.%s", k.Encode())
 }
 
+// GetNegativeCacheKey returns a string representation for the cache key to mark the entity is missing
+func GetNegativeCacheKey(k *datastore.Key) string {
+	return fmt.Sprintf("datastore.missing.%s", k.Encode())
+}
+
+// KindCacheTag returns a cache tag name for the entities of `kind`
+func KindCacheTag(kind string) string {
+	return fmt.Sprintf("datastore.kind:%s", kind)
+}
+
+// GetCacheTags is a cache.Tagger for the cache keys returned by GetCacheKey and GetNegativeCacheKey.
+// The entries are tagged by their kinds.
+func GetCacheTags(cacheKey string) []string {
+	const prefix = "datastore."
+	if !strings.HasPrefix(cacheKey, prefix) {
+		return nil
+	}
+	encoded := strings.TrimPrefix(strings.TrimPrefix(cacheKey, prefix), "missing.")
+	k, err := datastore.DecodeKey(encoded)
+	if err != nil {
+		return nil
+	}
+	return []string{KindCacheTag(k.Kind)}
+}
+
+// NewTaggedCache returns a *cache.Tagged that tags entries by kind
+// so that (*cache.Tagged).Invalidate(ctx, KindCacheTag(kind)) invalidates all entities in the kind.
+// The Cache option uses it automatically for the caches not supporting tags.
+func NewTaggedCache(c cache.Cache) *cache.Tagged {
+	return cache.NewTagged(c, cache.WithTagger(GetCacheTags))
+}
+
 // IsDatastoreError returns true if err is not ErrNoSuchEntity
 func IsDatastoreError(err error) bool {
 	if err == nil {
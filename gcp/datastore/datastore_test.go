@@ -1,9 +1,13 @@
 package datastore
 
 import (
+	"context"
 	"log"
 	"os"
 	"testing"
+
+	"github.com/yssk22/go/cache"
+	"github.com/yssk22/go/x/xtesting/assert"
 )
 
 var testEnv *TestEnv
@@ -25,3 +29,32 @@ func TestMain(m *testing.M) {
 type Example struct {
 	ID string
 }
+
+func TestGetCacheTags(t *testing.T) {
+	a := assert.New(t)
+	tags := GetCacheTags(GetCacheKey(NewKey("Example", "example-1")))
+	a.EqInt(1, len(tags))
+	a.EqStr(KindCacheTag("Example"), tags[0])
+	tags = GetCacheTags(GetNegativeCacheKey(NewKey("Example", "example-1")))
+	a.EqInt(1, len(tags))
+	a.EqStr(KindCacheTag("Example"), tags[0])
+	a.EqInt(0, len(GetCacheTags("invalid")))
+}
+
+func TestCache_tagsByKind(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	instrumented := cache.Instrumented(&cache.MemoryCache{})
+	c := newClientConfig(Cache(instrumented)).Cache
+	a.OK(cache.SupportsTags(c))
+
+	key := GetCacheKey(NewKey("Example", "example-1"))
+	a.Nil(c.SetMulti(ctx, []string{key}, []*Example{{ID: "example-1"}}))
+	a.Nil(c.(cache.TagInvalidator).InvalidateTags(ctx, KindCacheTag("Example")))
+	dst := make([]*Example, 1)
+	a.NotNil(c.GetMulti(ctx, []string{key}, dst))
+
+	// a cache supporting tags is used as is even if wrapped
+	tagged := cache.Instrumented(NewTaggedCache(&cache.MemoryCache{}))
+	a.OK(tagged == newClientConfig(Cache(tagged)).Cache)
+}
@@ -0,0 +1,37 @@
+package datastore
+
+import (
+	"errors"
+
+	"cloud.google.com/go/datastore"
+	"github.com/yssk22/go/retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	retry.RegisterClassifier(func(err error) (bool, bool) {
+		if IsContentionError(err) {
+			return true, true
+		}
+		return false, false
+	})
+}
+
+// IsContentionError returns true if the error is caused by the contention of transactions or entity groups.
+// Such errors are classified as retryable by retry.IsRetryable.
+func IsContentionError(err error) bool {
+	if errors.Is(err, datastore.ErrConcurrentTransaction) {
+		return true
+	}
+	var s interface {
+		GRPCStatus() *status.Status
+	}
+	if errors.As(err, &s) {
+		switch s.GRPCStatus().Code() {
+		case codes.Aborted, codes.Unavailable:
+			return true
+		}
+	}
+	return false
+}
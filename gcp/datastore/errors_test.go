@@ -0,0 +1,22 @@
+package datastore
+
+import (
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/yssk22/go/retry"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsContentionError(t *testing.T) {
+	a := assert.New(t)
+	a.OK(IsContentionError(datastore.ErrConcurrentTransaction))
+	a.OK(IsContentionError(fmt.Errorf("wrapped: %w", datastore.ErrConcurrentTransaction)))
+	a.OK(IsContentionError(status.Error(codes.Aborted, "too much contention")))
+	a.OK(!IsContentionError(status.Error(codes.InvalidArgument, "invalid")))
+	a.OK(!IsContentionError(datastore.ErrNoSuchEntity))
+	a.OK(retry.IsRetryable(status.Error(codes.Aborted, "too much contention")))
+}
@@ -0,0 +1,69 @@
+package kvstore
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	ds "github.com/yssk22/go/gcp/datastore"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// backend is the storage of the key values.
+type backend interface {
+	name() string
+	load(ctx context.Context) (map[string]string, error)
+	put(ctx context.Context, key string, value string) error
+	delete(ctx context.Context, key string) error
+}
+
+// New returns a new *Store for the entities of `kind` in the datastore.
+// `ctx` is used for the datastore operations since keyvalue.Getter does not take a context.
+func New(ctx context.Context, client *ds.Client, kind string, options ...Option) *Store {
+	return newStore(ctx, &datastoreBackend{
+		client: client,
+		kind:   kind,
+	}, options...)
+}
+
+// Entity is the datastore entity for a key value.
+type Entity struct {
+	Value     string `datastore:",noindex"`
+	UpdatedAt time.Time
+}
+
+type datastoreBackend struct {
+	client *ds.Client
+	kind   string
+}
+
+func (b *datastoreBackend) name() string {
+	return b.kind
+}
+
+func (b *datastoreBackend) load(ctx context.Context) (map[string]string, error) {
+	var entities []*Entity
+	keys, err := b.client.GetAll(ctx, ds.NewQuery(b.kind), &entities)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for i, k := range keys {
+		values[k.Name] = entities[i].Value
+	}
+	return values, nil
+}
+
+func (b *datastoreBackend) put(ctx context.Context, key string, value string) error {
+	_, err := b.client.PutMulti(ctx, []*datastore.Key{ds.NewKey(b.kind, key)}, []*Entity{
+		{
+			Value:     value,
+			UpdatedAt: xtime.Now(),
+		},
+	})
+	return err
+}
+
+func (b *datastoreBackend) delete(ctx context.Context, key string) error {
+	return b.client.DeleteMulti(ctx, []*datastore.Key{ds.NewKey(b.kind, key)})
+}
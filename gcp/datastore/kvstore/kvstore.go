@@ -0,0 +1,213 @@
+// Package kvstore provides a keyvalue.GetterSetter backed by a datastore kind so that operators
+// can change settings at runtime across all instances.
+//
+// Each key is stored as an entity whose key name is the key and the value is encoded in JSON.
+// All entities of the kind are loaded at once and cached by cache.Cache for a short TTL,
+// so the changes made by other instances are visible after the TTL at most.
+//
+//     store := kvstore.New(ctx, client, "RuntimeConfig", kvstore.Cache(memcache))
+//     config.Setup(store, config.EnvVar, config.YAMLFile("config.yaml"))
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/cache"
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xlog"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "gcp.datastore.kvstore"
+
+// DefaultTTL is the default TTL of the cached values
+const DefaultTTL = 30 * time.Second
+
+// Option is a function to configure *Store
+type Option func(*Store) *Store
+
+// TTL returns an Option to set how long the loaded values are used. The default is DefaultTTL.
+func TTL(d time.Duration) Option {
+	return func(s *Store) *Store {
+		s.ttl = d
+		return s
+	}
+}
+
+// Cache returns an Option to share the loaded values by `c`, e.g. memcache.
+// The default is a cache.MemoryCache in the process.
+func Cache(c cache.Cache) Option {
+	return func(s *Store) *Store {
+		s.cache = c
+		return s
+	}
+}
+
+// Clock returns an Option to set the clock to check the TTL.
+func Clock(c xtime.Clock) Option {
+	return func(s *Store) *Store {
+		s.clock = c
+		return s
+	}
+}
+
+// Store is a keyvalue.GetterSetter backed by a datastore kind.
+type Store struct {
+	ctx     context.Context
+	backend backend
+	ttl     time.Duration
+	cache   cache.Cache
+	clock   xtime.Clock
+
+	mu       sync.Mutex
+	snapshot *Snapshot
+}
+
+// Snapshot is the values of the kind loaded at a time. This is the value stored in the cache.
+type Snapshot struct {
+	Values map[string]string
+	Expiry time.Time
+}
+
+func newStore(ctx context.Context, b backend, options ...Option) *Store {
+	s := &Store{
+		ctx:     ctx,
+		backend: b,
+		ttl:     DefaultTTL,
+		cache:   &cache.MemoryCache{},
+		clock:   xtime.SystemClock,
+	}
+	for _, f := range options {
+		s = f(s)
+	}
+	return s
+}
+
+// String returns the source name for keyvalue.Provenance
+func (s *Store) String() string {
+	return fmt.Sprintf("datastore:%s", s.backend.name())
+}
+
+// Get implements keyvalue.Getter#Get.
+// If the values cannot be loaded or the value is broken, the error is logged and keyvalue.KeyError is returned
+// so that the lookups fall through to the lower precedence sources in a keyvalue.List.
+func (s *Store) Get(key interface{}) (interface{}, error) {
+	skey, ok := key.(string)
+	if !ok {
+		return nil, keyvalue.KeyError(fmt.Sprintf("%s (not string)", key))
+	}
+	snapshot, err := s.load()
+	if err != nil {
+		_, logger := xlog.WithContextAndKey(s.ctx, "", LoggerKey)
+		logger.Errorf("could not load %s, fall through %q: %v", s, skey, err)
+		return nil, keyvalue.KeyError(skey)
+	}
+	encoded, ok := snapshot.Values[skey]
+	if !ok {
+		return nil, keyvalue.KeyError(skey)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(encoded), &v); err != nil {
+		_, logger := xlog.WithContextAndKey(s.ctx, "", LoggerKey)
+		logger.Errorf("could not decode the value for %q in %s: %v", skey, s, err)
+		return nil, keyvalue.KeyError(skey)
+	}
+	return v, nil
+}
+
+// Set implements keyvalue.Setter#Set. The value is encoded in JSON.
+func (s *Store) Set(key interface{}, v interface{}) error {
+	skey, ok := key.(string)
+	if !ok {
+		return keyvalue.KeyError(fmt.Sprintf("%s (not string)", key))
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not encode the value for %q: %w", skey, err)
+	}
+	if err := s.backend.put(s.ctx, skey, string(encoded)); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Del deletes the value for `key`
+func (s *Store) Del(key interface{}) error {
+	skey, ok := key.(string)
+	if !ok {
+		return keyvalue.KeyError(fmt.Sprintf("%s (not string)", key))
+	}
+	if err := s.backend.delete(s.ctx, skey); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Keys implements keyvalue.Enumerable#Keys
+func (s *Store) Keys() []interface{} {
+	snapshot, err := s.load()
+	if err != nil {
+		return nil
+	}
+	keys := make([]interface{}, 0, len(snapshot.Values))
+	for k := range snapshot.Values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *Store) cacheKey() string {
+	return fmt.Sprintf("kvstore.%s", s.backend.name())
+}
+
+// load returns the snapshot in the process, or in the cache, or loads a new one from datastore.
+func (s *Store) load() (*Snapshot, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot != nil && now.Before(s.snapshot.Expiry) {
+		return s.snapshot, nil
+	}
+	cached := make([]*Snapshot, 1)
+	if err := s.cache.GetMulti(s.ctx, []string{s.cacheKey()}, cached); err == nil && cached[0] != nil && now.Before(cached[0].Expiry) {
+		s.snapshot = cached[0]
+		return s.snapshot, nil
+	}
+	values, err := s.backend.load(s.ctx)
+	if err != nil {
+		if s.snapshot != nil {
+			// keep using the stale values rather than failing all the config lookups.
+			_, logger := xlog.WithContextAndKey(s.ctx, "", LoggerKey)
+			logger.Warnf("could not reload %s, use the stale values: %v", s, err)
+			return s.snapshot, nil
+		}
+		return nil, err
+	}
+	s.snapshot = &Snapshot{
+		Values: values,
+		Expiry: now.Add(s.ttl),
+	}
+	if err := s.cache.SetMulti(s.ctx, []string{s.cacheKey()}, []*Snapshot{s.snapshot}); err != nil {
+		_, logger := xlog.WithContextAndKey(s.ctx, "", LoggerKey)
+		logger.Warnf("could not write %s to the cache: %v", s, err)
+	}
+	return s.snapshot, nil
+}
+
+// invalidate drops the snapshot so that the next Get loads the latest values.
+func (s *Store) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = nil
+	if err := s.cache.DeleteMulti(s.ctx, []string{s.cacheKey()}); err != nil {
+		_, logger := xlog.WithContextAndKey(s.ctx, "", LoggerKey)
+		logger.Warnf("could not invalidate %s in the cache: %v", s, err)
+	}
+}
@@ -0,0 +1,121 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/cache"
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+type memoryBackend struct {
+	values map[string]string
+	loads  int
+	err    error
+}
+
+func (b *memoryBackend) name() string {
+	return "Test"
+}
+
+func (b *memoryBackend) load(ctx context.Context) (map[string]string, error) {
+	b.loads++
+	if b.err != nil {
+		return nil, b.err
+	}
+	values := make(map[string]string)
+	for k, v := range b.values {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func (b *memoryBackend) put(ctx context.Context, key string, value string) error {
+	b.values[key] = value
+	return nil
+}
+
+func (b *memoryBackend) delete(ctx context.Context, key string) error {
+	delete(b.values, key)
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &memoryBackend{values: map[string]string{"db.host": `"db.example.com"`}}
+	s := newStore(context.Background(), b, Clock(clock), TTL(time.Minute))
+
+	a.EqStr("db.example.com", keyvalue.GetStringOr(s, "db.host", ""))
+	a.EqInt(1, b.loads)
+
+	a.Nil(s.Set("db.port", 5432))
+	a.EqInt(5432, keyvalue.GetIntOr(s, "db.port", 0))
+	a.EqInt(2, b.loads)
+	a.EqInt(2, len(s.Keys()))
+
+	a.Nil(s.Del("db.port"))
+	_, err := s.Get("db.port")
+	a.OK(keyvalue.IsKeyError(err))
+	a.EqStr("datastore:Test", s.String())
+}
+
+func TestStore_ttl(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &memoryBackend{values: map[string]string{"enabled": "true"}}
+	shared := &cache.MemoryCache{}
+	s1 := newStore(context.Background(), b, Clock(clock), Cache(shared))
+	s2 := newStore(context.Background(), b, Clock(clock), Cache(shared))
+
+	a.OK(keyvalue.GetOr(s1, "enabled", false).(bool))
+	a.OK(keyvalue.GetOr(s2, "enabled", false).(bool))
+	a.EqInt(1, b.loads) // s2 uses the values in the shared cache
+
+	// changed by another instance
+	b.values["enabled"] = "false"
+	a.OK(keyvalue.GetOr(s1, "enabled", false).(bool))
+
+	clock.Advance(DefaultTTL)
+	a.OK(!keyvalue.GetOr(s1, "enabled", true).(bool))
+	a.EqInt(2, b.loads)
+
+	// stale values are used when the datastore is not available
+	b.err = errors.New("unavailable")
+	clock.Advance(DefaultTTL)
+	a.OK(!keyvalue.GetOr(s1, "enabled", true).(bool))
+}
+
+func TestStore_coldStartFailure(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &memoryBackend{
+		values: map[string]string{"db.host": `"db.example.com"`, "broken": `{`},
+		err:    errors.New("unavailable"),
+	}
+	s := newStore(context.Background(), b, Clock(clock))
+	list := keyvalue.NewList(s, keyvalue.StringKeyMap(map[string]interface{}{
+		"db.host": "env.example.com",
+		"broken":  "fallback",
+	}))
+	_, err := s.Get("db.host")
+	a.OK(keyvalue.IsKeyError(err))
+	v, err := list.Get("db.host")
+	a.Nil(err)
+	a.EqStr("env.example.com", v.(string))
+
+	// recovered
+	b.err = nil
+	v, err = list.Get("db.host")
+	a.Nil(err)
+	a.EqStr("db.example.com", v.(string))
+
+	// a broken value falls through
+	v, err = list.Get("broken")
+	a.Nil(err)
+	a.EqStr("fallback", v.(string))
+}
@@ -204,7 +204,7 @@ func NewTestEnv() (*TestEnv, error) {
 	// }
 	return &TestEnv{
 		context:  ctx,
-		memcache: &cache.MemoryCache{},
+		memcache: NewTaggedCache(&cache.MemoryCache{}),
 		emulator: emulator,
 	}, nil
 }
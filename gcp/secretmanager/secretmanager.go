@@ -0,0 +1,57 @@
+// Package secretmanager provides a client for Google Cloud Secret Manager that implements config.SecretManagerClient.
+package secretmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"google.golang.org/api/option"
+	secretmanagerpb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
+)
+
+// Client is a wrapper for secretmanager.Client
+type Client struct {
+	inner     *secretmanager.Client
+	projectID string
+}
+
+// NewClient returns a new *Client to access the secrets in `projectID`
+func NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*Client, error) {
+	inner, err := secretmanager.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		inner:     inner,
+		projectID: projectID,
+	}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.inner.Close()
+}
+
+// AccessSecret returns the secret data for `name`. The name can be a secret ID (the latest version is used),
+// `{secret ID}/versions/{version}` or a full resource name starting with `projects/`.
+func (c *Client) AccessSecret(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.inner.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: c.resourceName(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload.Data, nil
+}
+
+func (c *Client) resourceName(name string) string {
+	if strings.HasPrefix(name, "projects/") {
+		return name
+	}
+	if !strings.Contains(name, "/versions/") {
+		name = name + "/versions/latest"
+	}
+	return fmt.Sprintf("projects/%s/secrets/%s", c.projectID, name)
+}
@@ -0,0 +1,15 @@
+package secretmanager
+
+import (
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestClient_resourceName(t *testing.T) {
+	a := assert.New(t)
+	c := &Client{projectID: "my-project"}
+	a.EqStr("projects/my-project/secrets/db-password/versions/latest", c.resourceName("db-password"))
+	a.EqStr("projects/my-project/secrets/db-password/versions/3", c.resourceName("db-password/versions/3"))
+	a.EqStr("projects/other/secrets/db-password/versions/1", c.resourceName("projects/other/secrets/db-password/versions/1"))
+}
@@ -1,8 +1,10 @@
 module github.com/yssk22/go
 
 require (
+	cloud.google.com/go v0.56.0
 	cloud.google.com/go/datastore v1.1.0
 	cloud.google.com/go/logging v1.0.0
+	github.com/BurntSushi/toml v0.3.1
 	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/cheggaaa/pb v2.0.6+incompatible // indirect
 	github.com/codegangsta/cli v1.20.0 // indirect
@@ -19,8 +21,9 @@ require (
 	golang.org/x/tools v0.0.0-20200504215816-9f0e5ee6c7c4
 	google.golang.org/api v0.23.0
 	google.golang.org/appengine v1.6.6
-	google.golang.org/genproto v0.0.0-20200430143042-b979b6f78d84 // indirect
+	google.golang.org/genproto v0.0.0-20200430143042-b979b6f78d84
 	google.golang.org/grpc v1.29.1
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 go 1.13
@@ -0,0 +1,275 @@
+package keyvalue
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// FieldError is an error for a field reported by Bind.
+type FieldError struct {
+	Field string
+	Key   string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Field, e.Key, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Bind populates the fields of the struct pointed by `dst` from `g` by the struct tags.
+//
+//     type Config struct {
+//         Host    string        `config:"db.host" default:"localhost"`
+//         Timeout time.Duration `config:"db.timeout" default:"10s"`
+//         Token   string        `config:"api.token" required:"true"`
+//         Cache   struct {
+//             Size int `config:"size"`
+//         } `config:"cache"`
+//     }
+//
+// The nested structs are populated with the keys prefixed by their tags (`cache.size` in the above).
+// string, bool, int, uint, float, time.Duration, time.Time (RFC3339 or YYYY/MM/DD), Secret and their slices are supported.
+// The fields without values nor defaults are kept as they are.
+// All missing required keys and conversion errors are returned together as xerrors.MultiError of *FieldError.
+func Bind(g Getter, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dst must be a pointer to struct but %T", dst)
+	}
+	var errors xerrors.MultiError
+	bindStruct(g, v.Elem(), "", "", &errors)
+	return errors.ToReturn()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func bindStruct(g Getter, v reflect.Value, keyPrefix string, fieldPrefix string, errors *xerrors.MultiError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+		fieldName := fieldPrefix + field.Name
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType && field.Type != secretType {
+			prefix := keyPrefix
+			if key != "" {
+				prefix = keyPrefix + key + "."
+			}
+			bindStruct(g, v.Field(i), prefix, fieldName+".", errors)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		key = keyPrefix + key
+		value, err := g.Get(key)
+		if err != nil && !IsKeyError(err) {
+			*errors = append(*errors, &FieldError{Field: fieldName, Key: key, Err: err})
+			continue
+		}
+		if value == nil {
+			if def, ok := field.Tag.Lookup("default"); ok {
+				value = def
+			} else {
+				if required, _ := strconv.ParseBool(field.Tag.Get("required")); required {
+					*errors = append(*errors, &FieldError{Field: fieldName, Key: key, Err: KeyError(key)})
+				}
+				continue
+			}
+		}
+		converted, err := convert(value, field.Type)
+		if err != nil {
+			*errors = append(*errors, &FieldError{Field: fieldName, Key: key, Err: err})
+			continue
+		}
+		v.Field(i).Set(converted)
+	}
+}
+
+// convert converts a value from Getter to the type `t`.
+func convert(value interface{}, t reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return v, fmt.Errorf("could not convert nil to %s", t)
+	}
+	if v.Type() == t {
+		return v, nil
+	}
+	switch {
+	case t == secretType:
+		s, err := convert(value, reflect.TypeOf(""))
+		if err != nil {
+			return v, err
+		}
+		return reflect.ValueOf(NewSecret(s.String())), nil
+	case t == durationType:
+		switch vv := value.(type) {
+		case string:
+			d, err := time.ParseDuration(strings.TrimSpace(vv))
+			if err != nil {
+				return v, err
+			}
+			return reflect.ValueOf(d), nil
+		}
+	case t == timeType:
+		if s, ok := value.(string); ok {
+			s = strings.TrimSpace(s)
+			if tt, err := xtime.Parse(s); err == nil {
+				return reflect.ValueOf(tt), nil
+			}
+			tt, err := xtime.ParseDateDefault(s)
+			if err != nil {
+				return v, fmt.Errorf("could not parse %q as time", s)
+			}
+			return reflect.ValueOf(tt), nil
+		}
+	case t.Kind() == reflect.Ptr:
+		elem, err := convert(value, t.Elem())
+		if err != nil {
+			return v, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(elem)
+		return p, nil
+	case t.Kind() == reflect.Slice:
+		var elems []interface{}
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				elems = append(elems, v.Index(i).Interface())
+			}
+		case reflect.String:
+			if s := strings.TrimSpace(v.String()); s != "" {
+				for _, e := range strings.Split(s, ",") {
+					elems = append(elems, strings.TrimSpace(e))
+				}
+			}
+		default:
+			elems = append(elems, value)
+		}
+		slice := reflect.MakeSlice(t, len(elems), len(elems))
+		for i, e := range elems {
+			ev, err := convert(e, t.Elem())
+			if err != nil {
+				return v, fmt.Errorf("[%d]: %v", i, err)
+			}
+			slice.Index(i).Set(ev)
+		}
+		return slice, nil
+	}
+	if s, ok := value.(Secret); ok {
+		// the secret is revealed only when it is explicitly converted to a string.
+		// other conversions are rejected so that the errors never contain the plaintext.
+		if t.Kind() != reflect.String {
+			return v, fmt.Errorf("cannot convert %s to %s", Redacted, t)
+		}
+		return reflect.ValueOf(s.Value()).Convert(t), nil
+	}
+	if s, ok := value.([]string); ok && t.Kind() != reflect.Slice {
+		// Take the first element follwoing to url.Values implementation
+		if len(s) == 0 {
+			return v, fmt.Errorf("empty value")
+		}
+		return convert(s[0], t)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		switch v.Kind() {
+		case reflect.String:
+			return v.Convert(t), nil
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(fmt.Sprint(value)).Convert(t), nil
+		}
+	case reflect.Bool:
+		switch v.Kind() {
+		case reflect.Bool:
+			return v.Convert(t), nil
+		case reflect.String:
+			b, err := strconv.ParseBool(strings.TrimSpace(v.String()))
+			if err != nil {
+				return v, err
+			}
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := reflect.New(t).Elem()
+			if n.OverflowInt(v.Int()) {
+				return v, fmt.Errorf("%d overflows %s", v.Int(), t)
+			}
+			n.SetInt(v.Int())
+			return n, nil
+		case reflect.Float32, reflect.Float64:
+			// e.g. numbers in JSON documents
+			f := v.Float()
+			if f != math.Trunc(f) || reflect.New(t).Elem().OverflowInt(int64(f)) {
+				return v, fmt.Errorf("could not convert %v to %s", f, t)
+			}
+			return reflect.ValueOf(int64(f)).Convert(t), nil
+		case reflect.String:
+			i, err := strconv.ParseInt(strings.TrimSpace(v.String()), 10, t.Bits())
+			if err != nil {
+				return v, err
+			}
+			return reflect.ValueOf(i).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n := reflect.New(t).Elem()
+			if n.OverflowUint(v.Uint()) {
+				return v, fmt.Errorf("%d overflows %s", v.Uint(), t)
+			}
+			n.SetUint(v.Uint())
+			return n, nil
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			if f < 0 || f != math.Trunc(f) || reflect.New(t).Elem().OverflowUint(uint64(f)) {
+				return v, fmt.Errorf("could not convert %v to %s", f, t)
+			}
+			return reflect.ValueOf(uint64(f)).Convert(t), nil
+		case reflect.String:
+			i, err := strconv.ParseUint(strings.TrimSpace(v.String()), 10, t.Bits())
+			if err != nil {
+				return v, err
+			}
+			return reflect.ValueOf(i).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return v.Convert(t), nil
+		case reflect.String:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.String()), t.Bits())
+			if err != nil {
+				return v, err
+			}
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	}
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	return v, fmt.Errorf("could not convert %T to %s", value, t)
+}
@@ -0,0 +1,78 @@
+package keyvalue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestBind(t *testing.T) {
+	a := assert.New(t)
+	type DB struct {
+		Host    string        `config:"host" default:"localhost"`
+		Port    int           `config:"port"`
+		Timeout time.Duration `config:"timeout" default:"10s"`
+	}
+	var cfg struct {
+		DB      DB        `config:"db"`
+		Debug   bool      `config:"debug"`
+		Rate    float64   `config:"rate"`
+		Hosts   []string  `config:"hosts"`
+		Ports   []int     `config:"ports"`
+		Since   time.Time `config:"since"`
+		Keep    string    `config:"keep"`
+		Ignored string    `config:"-"`
+		NoTag   string
+	}
+	cfg.Keep = "kept"
+	err := Bind(Map{
+		"db.port": "5432",
+		"debug":   "true",
+		"rate":    1,
+		"hosts":   "a.example.com, b.example.com",
+		"ports":   []string{"80", "443"},
+		"since":   "2020/01/02",
+		"NoTag":   "ignored",
+	}, &cfg)
+	a.Nil(err)
+	a.EqStr("localhost", cfg.DB.Host)
+	a.EqInt(5432, cfg.DB.Port)
+	a.OK(cfg.DB.Timeout == 10*time.Second)
+	a.OK(cfg.Debug)
+	a.OK(cfg.Rate == 1)
+	a.EqInt(2, len(cfg.Hosts))
+	a.EqStr("b.example.com", cfg.Hosts[1])
+	a.EqInt(2, len(cfg.Ports))
+	a.EqInt(443, cfg.Ports[1])
+	a.OK(cfg.Since.Equal(xtime.MustParseDateDefault("2020/01/02")))
+	a.EqStr("kept", cfg.Keep)
+	a.EqStr("", cfg.NoTag)
+}
+
+func TestBind_Errors(t *testing.T) {
+	a := assert.New(t)
+	var cfg struct {
+		Token   string        `config:"token" required:"true"`
+		Port    int           `config:"port"`
+		Timeout time.Duration `config:"timeout"`
+		Debug   bool          `config:"debug"`
+	}
+	err := Bind(Map{
+		"port":    "http",
+		"timeout": "10",
+		"debug":   true,
+	}, &cfg)
+	a.NotNil(err)
+	errors := err.(xerrors.MultiError)
+	a.EqInt(3, len(errors))
+	a.EqStr("Token", errors[0].(*FieldError).Field)
+	a.OK(IsKeyError(errors[0].(*FieldError).Err))
+	a.EqStr("port", errors[1].(*FieldError).Key)
+	a.EqStr("timeout", errors[2].(*FieldError).Key)
+	a.OK(cfg.Debug)
+
+	a.NotNil(Bind(Map{}, cfg))
+}
@@ -1,11 +1,16 @@
 // Package config provies configuraiton access functions
 package config
 
-import "github.com/yssk22/go/keyvalue"
+import (
+	"time"
+
+	"github.com/yssk22/go/keyvalue"
+)
 
 var defaultList = keyvalue.NewList()
 
-// Setup initialize the Getters for Get* funcitons
+// Setup initialize the Getters for Get* funcitons.
+// Getters are given in precedence order, e.g. `Setup(EnvVar, YAMLFile("config.local.yaml", Optional()), YAMLFile("config.yaml"))`
 func Setup(g ...keyvalue.Getter) {
 	defaultList = keyvalue.NewList(g...)
 }
@@ -29,3 +34,69 @@ func GetStringOr(key string, or string) string {
 func GetIntOr(key string, or int) int {
 	return defaultList.GetIntOr(key, or)
 }
+
+// GetString returns a string value from the default config list. See keyvalue.GetString for details.
+func GetString(key string) (string, error) {
+	return defaultList.GetString(key)
+}
+
+// GetInt is a int version of GetString
+func GetInt(key string) (int, error) {
+	return defaultList.GetInt(key)
+}
+
+// GetFloat is a float64 version of GetString
+func GetFloat(key string) (float64, error) {
+	return defaultList.GetFloat(key)
+}
+
+// GetBool is a bool version of GetString
+func GetBool(key string) (bool, error) {
+	return defaultList.GetBool(key)
+}
+
+// GetDuration is a time.Duration version of GetString
+func GetDuration(key string) (time.Duration, error) {
+	return defaultList.GetDuration(key)
+}
+
+// GetStringSlice is a []string version of GetString
+func GetStringSlice(key string) ([]string, error) {
+	return defaultList.GetStringSlice(key)
+}
+
+// GetTime is a time.Time version of GetString
+func GetTime(key string) (time.Time, error) {
+	return defaultList.GetTime(key)
+}
+
+// GetSecret is a keyvalue.Secret version of GetString
+func GetSecret(key string) (keyvalue.Secret, error) {
+	return defaultList.GetSecret(key)
+}
+
+// Lookup returns a value from the default config list with the source that supplied it.
+func Lookup(key string) (interface{}, *keyvalue.Provenance, error) {
+	return defaultList.Lookup(key)
+}
+
+// Bind populates the struct pointed by `dst` from the default config list. See keyvalue.Bind for details.
+func Bind(dst interface{}) error {
+	return keyvalue.Bind(defaultList, dst)
+}
+
+// Watch registers `f` to be called when the value for `key` is changed by Reload or polling.
+// It should be called after Setup since Setup replaces the list of Getters.
+func Watch(key string, f func(old, new interface{})) func() {
+	return defaultList.Watch(key, f)
+}
+
+// Reload reloads the keyvalue.Watchable sources, such as files, and notifies the changes to the watchers.
+func Reload() error {
+	return defaultList.Reload()
+}
+
+// StartPolling reloads the sources every `interval` until the returned function is called.
+func StartPolling(interval time.Duration) func() {
+	return defaultList.StartPolling(interval)
+}
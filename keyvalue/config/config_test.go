@@ -22,3 +22,18 @@ func ExampleEnvVar_Get() {
 	// envfoobar
 	// invalid
 }
+
+func ExampleBind() {
+	os.Setenv("DB_HOST", "db.example.com")
+	Setup(EnvVar)
+	var cfg struct {
+		Host string `config:"db.host" default:"localhost"`
+		Port int    `config:"db.port" default:"5432"`
+	}
+	if err := Bind(&cfg); err != nil {
+		panic(err)
+	}
+	fmt.Println(cfg.Host, cfg.Port)
+	// Output:
+	// db.example.com 5432
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/yssk22/go/keyvalue"
+)
+
+// Redacted is a string to replace secret values in Dump.
+const Redacted = keyvalue.Redacted
+
+// SecretKeyPattern is a pattern of keys whose values are redacted in Dump.
+var SecretKeyPattern = regexp.MustCompile(`(?i)(secret|password|passwd|token|credential|private[._-]?key|api[._-]?key)`)
+
+// Dump writes the effective configuration with the sources to `w`.
+// The keys are collected from the keyvalue.Enumerable sources, such as files, and resolved by the precedence
+// so that the values overridden by environment variables are shown as well. The secret values, which are
+// keyvalue.Secret or for the keys matching SecretKeyPattern, are redacted.
+func Dump(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, key := range defaultList.Keys() {
+		v, p, err := defaultList.Lookup(key)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t(error: %v)\t\n", key, err)
+			continue
+		}
+		value := fmt.Sprint(v)
+		if SecretKeyPattern.MatchString(fmt.Sprint(key)) {
+			value = Redacted
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, value, p)
+	}
+	return tw.Flush()
+}
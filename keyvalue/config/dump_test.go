@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestDump(t *testing.T) {
+	a := assert.New(t)
+	os.Setenv("DB_HOST", "env.example.com")
+	defer os.Unsetenv("DB_HOST")
+	Setup(EnvVar, JSONFile("./testdata/config.json"), DotEnvFile("./testdata/config.env"))
+	defer Setup()
+	var buff bytes.Buffer
+	a.Nil(Dump(&buff))
+	lines := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(buff.String()), "\n") {
+		fields := strings.Fields(line)
+		lines[fields[0]] = strings.Join(fields[1:], " ")
+	}
+	a.EqStr("env.example.com #0 environment variables", lines["db.host"])
+	a.EqStr("5432 #1 file:./testdata/config.json", lines["db.port"])
+	a.EqStr("single # value #2 file:./testdata/config.env", lines["single"])
+
+	_, p, err := Lookup("quoted")
+	a.Nil(err)
+	a.EqStr("#2 file:./testdata/config.env", p.String())
+
+	os.Setenv("API_TOKEN", "secret")
+	defer os.Unsetenv("API_TOKEN")
+	Setup(EnvVar, JSONFile("./testdata/config.json"), JSONFile("./testdata/secret.json"))
+	buff.Reset()
+	a.Nil(Dump(&buff))
+	a.OK(strings.Contains(buff.String(), Redacted))
+	a.OK(!strings.Contains(buff.String(), "secret"), buff.String())
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"unicode"
 
 	"github.com/yssk22/go/keyvalue"
@@ -23,6 +24,10 @@ var EnvVar = keyvalue.NewList(&envVar{})
 type envVar struct {
 }
 
+func (e envVar) String() string {
+	return "environment variables"
+}
+
 func (e envVar) Get(key interface{}) (interface{}, error) {
 	var skey string
 	var ok bool
@@ -56,3 +61,8 @@ func getEnvVarName(key string) string {
 	}
 	return string(u)
 }
+
+// getKeyFromEnvVarName returns a dotted key for the environment variable name, e.g. `foo.bar` for `FOO_BAR`.
+func getKeyFromEnvVarName(name string) string {
+	return strings.Replace(strings.ToLower(name), "_", ".", -1)
+}
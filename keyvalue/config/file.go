@@ -0,0 +1,307 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/yssk22/go/keyvalue"
+	"gopkg.in/yaml.v2"
+)
+
+// Parser is a function to parse the file content into a (nested) map
+type Parser func([]byte) (map[string]interface{}, error)
+
+// FileOption is a function to configure *File
+type FileOption func(*File) *File
+
+// Optional returns a FileOption to allow the file not to exist.
+func Optional() FileOption {
+	return func(f *File) *File {
+		f.optional = true
+		return f
+	}
+}
+
+// File implements keyvalue.Getter for a configuration file.
+// Nested documents are flattened into dotted keys so that `{"db": {"host": "localhost"}}` is
+// accessible by `db.host` as well as by EnvVar.
+//
+// The file is loaded when the *File is created. If it fails, Get returns the error so that config.Get fails
+// instead of falling back to the next source silently. File implements keyvalue.Watchable to reload the changes.
+type File struct {
+	path     string
+	parser   Parser
+	optional bool
+	keyFunc  func(string) string
+	nameFunc func(string) string
+
+	mu     sync.RWMutex
+	values map[string]interface{}
+	err    error
+	stamp  fileStamp
+}
+
+// fileStamp is used to detect the file changes.
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// NewFile returns a new *File for the file at `path` parsed by `parser`
+func NewFile(path string, parser Parser, options ...FileOption) *File {
+	f := &File{
+		path:   path,
+		parser: parser,
+	}
+	for _, opt := range options {
+		f = opt(f)
+	}
+	f.Load()
+	return f
+}
+
+// JSONFile returns a *File for a JSON file
+func JSONFile(path string, options ...FileOption) *File {
+	return NewFile(path, ParseJSON, options...)
+}
+
+// YAMLFile returns a *File for a YAML file
+func YAMLFile(path string, options ...FileOption) *File {
+	return NewFile(path, ParseYAML, options...)
+}
+
+// TOMLFile returns a *File for a TOML file
+func TOMLFile(path string, options ...FileOption) *File {
+	return NewFile(path, ParseTOML, options...)
+}
+
+// DotEnvFile returns a *File for a .env file. The keys are looked up by the environment variable names
+// converted by the same rule as EnvVar.
+func DotEnvFile(path string, options ...FileOption) *File {
+	f := NewFile(path, ParseDotEnv, options...)
+	f.keyFunc = getEnvVarName
+	f.nameFunc = getKeyFromEnvVarName
+	return f
+}
+
+// String returns the description of the source
+func (f *File) String() string {
+	return fmt.Sprintf("file:%s", f.path)
+}
+
+// Keys implements keyvalue.Enumerable#Keys
+func (f *File) Keys() []interface{} {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	keys := make([]string, 0, len(f.values))
+	for k := range f.values {
+		if f.nameFunc != nil {
+			k = f.nameFunc(k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]interface{}, len(keys))
+	for i, k := range keys {
+		list[i] = k
+	}
+	return list
+}
+
+// Path returns the file path
+func (f *File) Path() string {
+	return f.path
+}
+
+// Load (re)loads the file content.
+func (f *File) Load() error {
+	values, stamp, err := f.load()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values = values
+	f.err = err
+	f.stamp = stamp
+	return err
+}
+
+// Poll implements keyvalue.Watchable#Poll.
+// The file is loaded again if the modification time or the size is changed.
+// If the new content cannot be loaded, the current values are kept and the error is returned.
+func (f *File) Poll() (func(), error) {
+	f.mu.RLock()
+	current := f.stamp
+	f.mu.RUnlock()
+	stamp, err := f.getStamp()
+	if err != nil {
+		return nil, err
+	}
+	if stamp == current {
+		return nil, nil
+	}
+	values, stamp, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.values = values
+		f.err = nil
+		f.stamp = stamp
+	}, nil
+}
+
+func (f *File) getStamp() (fileStamp, error) {
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fileStamp{}, nil
+		}
+		return fileStamp{}, err
+	}
+	return fileStamp{
+		exists:  true,
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+	}, nil
+}
+
+func (f *File) load() (map[string]interface{}, fileStamp, error) {
+	stamp, err := f.getStamp()
+	if err != nil {
+		return nil, stamp, fmt.Errorf("could not load config file %s: %v", f.path, err)
+	}
+	buff, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) && f.optional {
+			return map[string]interface{}{}, stamp, nil
+		}
+		return nil, stamp, fmt.Errorf("could not load config file %s: %v", f.path, err)
+	}
+	doc, err := f.parser(buff)
+	if err != nil {
+		return nil, stamp, fmt.Errorf("could not parse config file %s: %v", f.path, err)
+	}
+	values := make(map[string]interface{})
+	flatten("", doc, values)
+	return values, stamp, nil
+}
+
+// Get implements keyvalue.Getter#Get
+func (f *File) Get(key interface{}) (interface{}, error) {
+	skey, ok := key.(string)
+	if !ok {
+		return nil, keyvalue.KeyError(fmt.Sprintf("%s (not string)", key))
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	name := skey
+	if f.keyFunc != nil {
+		name = f.keyFunc(skey)
+	}
+	if v, ok := f.values[name]; ok {
+		return v, nil
+	}
+	return nil, keyvalue.KeyError(fmt.Sprintf("%s (%s)", skey, f.path))
+}
+
+// flatten sets the leaf values in `doc` into `values` with the dotted keys.
+func flatten(prefix string, doc map[string]interface{}, values map[string]interface{}) {
+	for k, v := range doc {
+		key := prefix + k
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			flatten(key+".", vv, values)
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(vv))
+			for kk, vvv := range vv {
+				m[fmt.Sprint(kk)] = vvv
+			}
+			flatten(key+".", m, values)
+		default:
+			values[key] = v
+		}
+	}
+}
+
+// ParseJSON is a Parser for JSON
+func ParseJSON(buff []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buff, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ParseYAML is a Parser for YAML
+func ParseYAML(buff []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(buff, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ParseTOML is a Parser for TOML
+func ParseTOML(buff []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(buff, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ParseDotEnv is a Parser for .env files that have `KEY=VALUE` lines.
+// `export ` prefixes, comments by '#' and single or double quoted values are supported.
+func ParseDotEnv(buff []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(buff))
+	var lineno int
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("line %d: invalid format", lineno)
+		}
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		switch {
+		case strings.HasPrefix(value, "\""):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("line %d: unterminated quote", lineno)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		doc[key] = value
+	}
+	return doc, scanner.Err()
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestFile(t *testing.T) {
+	a := assert.New(t)
+	for name, f := range map[string]*File{
+		"json": JSONFile("./testdata/config.json"),
+		"yaml": YAMLFile("./testdata/config.yaml"),
+		"toml": TOMLFile("./testdata/config.toml"),
+	} {
+		var cfg struct {
+			Host  string   `config:"db.host"`
+			Port  int      `config:"db.port"`
+			Hosts []string `config:"hosts"`
+		}
+		a.Nil(keyvalue.Bind(f, &cfg), name)
+		a.EqStr(name+".example.com", cfg.Host, name)
+		a.EqInt(5432, cfg.Port, name)
+		a.EqInt(2, len(cfg.Hosts), name)
+		_, err := f.Get("db")
+		a.OK(keyvalue.IsKeyError(err), name)
+	}
+}
+
+func TestDotEnvFile(t *testing.T) {
+	a := assert.New(t)
+	f := DotEnvFile("./testdata/config.env")
+	a.EqStr("env.example.com", keyvalue.GetStringOr(f, "db.host", ""))
+	a.EqInt(5432, keyvalue.GetIntOr(f, "db.port", 0))
+	a.EqStr(`a "quoted" value`, keyvalue.GetStringOr(f, "quoted", ""))
+	a.EqStr("single # value", keyvalue.GetStringOr(f, "single", ""))
+}
+
+func TestFile_Missing(t *testing.T) {
+	a := assert.New(t)
+	f := JSONFile("./testdata/missing.json")
+	_, err := f.Get("db.host")
+	a.NotNil(err)
+	a.OK(!keyvalue.IsKeyError(err))
+
+	f = JSONFile("./testdata/missing.json", Optional())
+	_, err = f.Get("db.host")
+	a.OK(keyvalue.IsKeyError(err))
+}
+
+func TestFile_Precedence(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "config")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "local.json")
+	a.Nil(ioutil.WriteFile(local, []byte(`{"db": {"host": "local"}}`), 0644))
+	Setup(
+		JSONFile(local),
+		JSONFile(filepath.Join(dir, "missing.json"), Optional()),
+		YAMLFile("./testdata/config.yaml"),
+	)
+	defer Setup()
+	a.EqStr("local", GetStringOr("db.host", ""))
+	a.EqInt(5432, GetIntOr("db.port", 0))
+}
+
+func TestFile_Reload(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "config")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	a.Nil(ioutil.WriteFile(path, []byte("log:\n  level: info\n"), 0644))
+	Setup(YAMLFile(path))
+	defer Setup()
+	var levels []string
+	Watch("log.level", func(old, new interface{}) {
+		levels = append(levels, fmt.Sprintf("%v", new))
+	})
+	a.Nil(Reload())
+	a.EqInt(0, len(levels))
+
+	a.Nil(ioutil.WriteFile(path, []byte("log:\n  level: debug\n"), 0644))
+	a.Nil(Reload())
+	a.EqInt(1, len(levels))
+	a.EqStr("debug", levels[0])
+
+	// broken content keeps the current value
+	a.Nil(ioutil.WriteFile(path, []byte("log: [\n"), 0644))
+	a.NotNil(Reload())
+	a.EqStr("debug", GetStringOr("log.level", ""))
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/yssk22/go/keyvalue"
+)
+
+// Flags returns a keyvalue.Getter for the flags explicitly set in the parsed `fs`, so that
+// `Setup(Flags(fs), EnvVar, YAMLFile("config.yaml"))` lets the command line override the others.
+// The key `foo.bar` is looked up by the flag name `foo.bar` or `foo-bar`.
+func Flags(fs *flag.FlagSet) keyvalue.Getter {
+	return &flags{
+		fs: fs,
+	}
+}
+
+type flags struct {
+	fs *flag.FlagSet
+}
+
+func (f *flags) String() string {
+	return "flags"
+}
+
+func (f *flags) Get(key interface{}) (interface{}, error) {
+	skey, ok := key.(string)
+	if !ok {
+		return nil, keyvalue.KeyError(fmt.Sprintf("%s (not string)", key))
+	}
+	var found *flag.Flag
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == skey || fl.Name == strings.Replace(skey, ".", "-", -1) {
+			found = fl
+		}
+	})
+	if found == nil {
+		return nil, keyvalue.KeyError(fmt.Sprintf("%s (flag)", skey))
+	}
+	if g, ok := found.Value.(flag.Getter); ok {
+		return g.Get(), nil
+	}
+	return found.Value.String(), nil
+}
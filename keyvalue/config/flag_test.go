@@ -0,0 +1,25 @@
+package config
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestFlags(t *testing.T) {
+	a := assert.New(t)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db-host", "default.example.com", "")
+	fs.Int("port", 80, "")
+	a.Nil(fs.Parse([]string{"-db-host", "flag.example.com"}))
+
+	list := keyvalue.NewList(Flags(fs), keyvalue.StringKeyMap(map[string]interface{}{
+		"db.host": "file.example.com",
+		"port":    5432,
+	}))
+	a.EqStr("flag.example.com", keyvalue.GetStringOr(list, "db.host", ""))
+	// not set explicitly so the default value of the flag is not used.
+	a.EqInt(5432, keyvalue.GetIntOr(list, "port", 0))
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncryptedPrefix is the prefix of the values encrypted by Keyring
+const EncryptedPrefix = "enc:v1:"
+
+// Keyring is a set of AES-256 keys by key IDs to decrypt `enc:v1:{keyID}:{base64(nonce+ciphertext)}` values
+// by AES-GCM. The key ID is used as the additional data so that the value cannot be decrypted by other keys.
+// Multiple keys can be held to rotate them.
+type Keyring map[string][]byte
+
+// ParseKeyring parses `{keyID}:{base64 key},...` formatted string, e.g. from an environment variable.
+func ParseKeyring(s string) (Keyring, error) {
+	keyring := make(Keyring)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idx := strings.Index(entry, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid keyring entry (must be {keyID}:{base64 key})")
+		}
+		id := entry[:idx]
+		key, err := base64.StdEncoding.DecodeString(entry[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key for %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid key for %q: must be 32 bytes but %d", id, len(key))
+		}
+		keyring[id] = key
+	}
+	return keyring, nil
+}
+
+// Encrypt encrypts `plaintext` by the key for `keyID` and returns the value to put in the configuration.
+func (k Keyring) Encrypt(keyID string, plaintext string) (string, error) {
+	aead, err := k.aead(keyID)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(keyID))
+	return fmt.Sprintf("%s%s:%s", EncryptedPrefix, keyID, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Resolve implements SecretResolver#Resolve to decrypt the `enc:v1:` values.
+func (k Keyring) Resolve(ctx context.Context, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, EncryptedPrefix) {
+		return "", false, nil
+	}
+	encoded := strings.TrimPrefix(value, EncryptedPrefix)
+	idx := strings.Index(encoded, ":")
+	if idx <= 0 {
+		return "", true, fmt.Errorf("invalid encrypted value format")
+	}
+	keyID := encoded[:idx]
+	aead, err := k.aead(keyID)
+	if err != nil {
+		return "", true, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded[idx+1:])
+	if err != nil {
+		return "", true, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", true, fmt.Errorf("invalid encrypted value: too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", true, fmt.Errorf("could not decrypt the value by the key %q: %w", keyID, err)
+	}
+	return string(plaintext), true, nil
+}
+
+func (k Keyring) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
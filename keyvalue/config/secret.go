@@ -0,0 +1,204 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// DefaultSecretTTL is the default duration to cache the resolved secrets.
+const DefaultSecretTTL = 5 * time.Minute
+
+// DefaultSecretResolveTimeout is the default timeout to resolve a secret.
+const DefaultSecretResolveTimeout = 30 * time.Second
+
+// SecretResolver is an interface to resolve a secret reference in a config value such as `enc:v1:...` or `secret://name`.
+type SecretResolver interface {
+	// Resolve returns the secret for `value`, or ok=false if `value` is not a reference for the resolver.
+	Resolve(ctx context.Context, value string) (secret string, ok bool, err error)
+}
+
+// Secrets returns a keyvalue.Getter that resolves the string values of `g` by `resolvers`.
+// The resolved values are returned as keyvalue.Secret so that they never appear in GetOr, Dump or logs,
+// and other values are returned as they are.
+//
+//     keyring, _ := config.ParseKeyring(os.Getenv("CONFIG_KEYRING"))
+//     config.Setup(config.Secrets(config.EnvVar, keyring, config.SecretManager(client)))
+//
+// The resolved values are cached for DefaultSecretTTL so that rotated secrets such as `secret://name` (the latest version)
+// are picked up. The cache can be configured by TTL, and is cleared by Flush or when the source is reloaded.
+// Concurrent Gets for the same value share one resolution, which times out after DefaultSecretResolveTimeout.
+func Secrets(g keyvalue.Getter, resolvers ...SecretResolver) *SecretGetter {
+	return &SecretGetter{
+		inner:     g,
+		resolvers: resolvers,
+		ttl:       DefaultSecretTTL,
+		timeout:   DefaultSecretResolveTimeout,
+		clock:     xtime.SystemClock,
+		resolved:  make(map[string]*resolvedSecret),
+		calls:     make(map[string]*secretCall),
+	}
+}
+
+// SecretGetter is a keyvalue.Getter returned by Secrets.
+type SecretGetter struct {
+	inner     keyvalue.Getter
+	resolvers []SecretResolver
+	ttl       time.Duration
+	timeout   time.Duration
+	clock     xtime.Clock
+
+	mu       sync.Mutex
+	resolved map[string]*resolvedSecret
+	calls    map[string]*secretCall // resolutions in flight by the value
+}
+
+type resolvedSecret struct {
+	secret keyvalue.Secret
+	expiry time.Time
+}
+
+// secretCall is a resolution in flight. done is closed when the result is set.
+type secretCall struct {
+	done   chan struct{}
+	secret keyvalue.Secret
+	ok     bool
+	err    error
+}
+
+// TTL sets the duration to cache the resolved secrets. 0 caches them until Flush or the source is reloaded.
+func (s *SecretGetter) TTL(ttl time.Duration) *SecretGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+	return s
+}
+
+// ResolveTimeout sets the timeout to resolve a secret. 0 resolves it without the timeout.
+func (s *SecretGetter) ResolveTimeout(timeout time.Duration) *SecretGetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeout = timeout
+	return s
+}
+
+// Flush clears the cache of the resolved secrets so that they are resolved again on the next Get.
+func (s *SecretGetter) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolved = make(map[string]*resolvedSecret)
+}
+
+func (s *SecretGetter) String() string {
+	if str, ok := s.inner.(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprintf("%T", s.inner)
+}
+
+func (s *SecretGetter) Get(key interface{}) (interface{}, error) {
+	v, err := s.inner.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	str, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	s.mu.Lock()
+	if cached, ok := s.resolved[str]; ok {
+		if cached.expiry.IsZero() || s.clock.Now().Before(cached.expiry) {
+			s.mu.Unlock()
+			return cached.secret, nil
+		}
+		delete(s.resolved, str)
+	}
+	c, inflight := s.calls[str]
+	if inflight {
+		s.mu.Unlock()
+		<-c.done
+	} else {
+		c = &secretCall{done: make(chan struct{})}
+		s.calls[str] = c
+		timeout := s.timeout
+		s.mu.Unlock()
+		s.resolve(str, c, timeout)
+	}
+	if c.err != nil {
+		// the error must not contain the value
+		return nil, fmt.Errorf("could not resolve the secret for %q: %w", key, c.err)
+	}
+	if c.ok {
+		return c.secret, nil
+	}
+	return v, nil
+}
+
+// resolve resolves `str` by the resolvers without the lock and caches the secret.
+func (s *SecretGetter) resolve(str string, c *secretCall, timeout time.Duration) {
+	completed := false
+	defer func() {
+		if !completed {
+			// the waiters must not use the value as it is when the resolver panics.
+			c.err = fmt.Errorf("secret resolver panicked")
+		}
+		s.mu.Lock()
+		delete(s.calls, str)
+		if c.ok && c.err == nil {
+			cached := &resolvedSecret{secret: c.secret}
+			if s.ttl > 0 {
+				cached.expiry = s.clock.Now().Add(s.ttl)
+			}
+			s.resolved[str] = cached
+		}
+		s.mu.Unlock()
+		close(c.done)
+	}()
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	for _, r := range s.resolvers {
+		resolved, ok, err := r.Resolve(ctx, str)
+		if err != nil {
+			c.err = err
+			break
+		}
+		if ok {
+			c.secret = keyvalue.NewSecret(resolved)
+			c.ok = true
+			break
+		}
+	}
+	completed = true
+}
+
+// Keys implements keyvalue.Enumerable#Keys if the source is keyvalue.Enumerable
+func (s *SecretGetter) Keys() []interface{} {
+	if e, ok := s.inner.(keyvalue.Enumerable); ok {
+		return e.Keys()
+	}
+	return nil
+}
+
+// Poll implements keyvalue.Watchable#Poll if the source is keyvalue.Watchable. The cache is cleared on apply.
+func (s *SecretGetter) Poll() (func(), error) {
+	w, ok := s.inner.(keyvalue.Watchable)
+	if !ok {
+		return nil, nil
+	}
+	apply, err := w.Poll()
+	if apply == nil {
+		return nil, err
+	}
+	return func() {
+		apply()
+		s.Flush()
+	}, err
+}
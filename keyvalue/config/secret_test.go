@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestKeyring(t *testing.T) {
+	a := assert.New(t)
+	keyring, err := ParseKeyring(fmt.Sprintf(
+		"k1:%s,k2:%s",
+		base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+	))
+	a.Nil(err)
+	encrypted, err := keyring.Encrypt("k1", "p@ssw0rd")
+	a.Nil(err)
+	a.OK(strings.HasPrefix(encrypted, "enc:v1:k1:"), encrypted)
+
+	plaintext, ok, err := keyring.Resolve(context.Background(), encrypted)
+	a.Nil(err)
+	a.OK(ok)
+	a.EqStr("p@ssw0rd", plaintext)
+
+	_, ok, _ = keyring.Resolve(context.Background(), "plain")
+	a.OK(!ok)
+
+	// the value encrypted by k1 cannot be decrypted by k2
+	_, ok, err = keyring.Resolve(context.Background(), strings.Replace(encrypted, ":k1:", ":k2:", 1))
+	a.OK(ok)
+	a.NotNil(err)
+
+	_, err = ParseKeyring("k1:c2hvcnQ=")
+	a.NotNil(err)
+}
+
+func TestSecrets(t *testing.T) {
+	a := assert.New(t)
+	keyring := Keyring{"k1": bytes.Repeat([]byte{1}, 32)}
+	encrypted, err := keyring.Encrypt("k1", "db-p@ssw0rd")
+	a.Nil(err)
+	source := keyvalue.StringKeyMap(map[string]interface{}{
+		"db.password": encrypted,
+		"api.key":     "secret://api-key",
+		"db.host":     "db.example.com",
+		"missing":     "secret://missing",
+		"db.port":     5432,
+	})
+	Setup(Secrets(source, keyring, SecretManager(StubSecretManager{
+		"api-key": "api-s3cret",
+	})))
+	defer Setup()
+
+	v, err := Get("db.password")
+	a.Nil(err)
+	a.EqStr(keyvalue.Redacted, fmt.Sprint(v))
+	a.EqStr(keyvalue.Redacted, fmt.Sprint(GetOr("api.key", "")))
+	a.EqStr("db.example.com", GetStringOr("db.host", ""))
+	a.EqInt(5432, GetIntOr("db.port", 0))
+
+	var cfg struct {
+		Password keyvalue.Secret `config:"db.password"`
+		APIKey   keyvalue.Secret `config:"api.key"`
+	}
+	a.Nil(Bind(&cfg))
+	a.EqStr("db-p@ssw0rd", cfg.Password.Value())
+	a.EqStr("api-s3cret", cfg.APIKey.Value())
+
+	_, err = Get("missing")
+	a.NotNil(err)
+
+	var buff bytes.Buffer
+	a.Nil(Dump(&buff))
+	a.OK(!strings.Contains(buff.String(), "p@ssw0rd"), buff.String())
+	a.OK(!strings.Contains(buff.String(), "api-s3cret"), buff.String())
+	a.OK(!strings.Contains(buff.String(), encrypted), buff.String())
+}
+
+func TestSecrets_TTL(t *testing.T) {
+	a := assert.New(t)
+	stub := StubSecretManager{"api-key": "v1"}
+	source := keyvalue.StringKeyMap(map[string]interface{}{
+		"api.key": "secret://api-key",
+	})
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := Secrets(source, SecretManager(stub)).TTL(time.Minute)
+	s.clock = clock
+	secret, err := keyvalue.GetSecret(s, "api.key")
+	a.Nil(err)
+	a.EqStr("v1", secret.Value())
+
+	// rotated
+	stub["api-key"] = "v2"
+	secret, _ = keyvalue.GetSecret(s, "api.key")
+	a.EqStr("v1", secret.Value())
+	clock.Advance(time.Minute)
+	secret, _ = keyvalue.GetSecret(s, "api.key")
+	a.EqStr("v2", secret.Value())
+
+	// no expiry with 0 TTL
+	s.TTL(0).Flush()
+	stub["api-key"] = "v3"
+	secret, _ = keyvalue.GetSecret(s, "api.key")
+	a.EqStr("v3", secret.Value())
+	stub["api-key"] = "v4"
+	clock.Advance(time.Hour)
+	secret, _ = keyvalue.GetSecret(s, "api.key")
+	a.EqStr("v3", secret.Value())
+	s.Flush()
+	secret, _ = keyvalue.GetSecret(s, "api.key")
+	a.EqStr("v4", secret.Value())
+}
+
+type resolverFunc func(ctx context.Context, value string) (string, bool, error)
+
+func (f resolverFunc) Resolve(ctx context.Context, value string) (string, bool, error) {
+	return f(ctx, value)
+}
+
+func TestSecrets_concurrent(t *testing.T) {
+	a := assert.New(t)
+	source := keyvalue.StringKeyMap(map[string]interface{}{
+		"api.key": "secret://api-key",
+		"slow":    "slow://key",
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	slow := resolverFunc(func(ctx context.Context, value string) (string, bool, error) {
+		if !strings.HasPrefix(value, "slow://") {
+			return "", false, nil
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return "slow-s3cret", true, nil
+	})
+	s := Secrets(source, slow, SecretManager(StubSecretManager{"api-key": "api-s3cret"}))
+	_, err := keyvalue.GetSecret(s, "api.key")
+	a.Nil(err)
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			secret, err := keyvalue.GetSecret(s, "slow")
+			a.Nil(err)
+			results[i] = secret.Value()
+		}(i)
+	}
+	<-started
+
+	// the cached secret is not blocked by the slow resolution
+	secret, err := keyvalue.GetSecret(s, "api.key")
+	a.Nil(err)
+	a.EqStr("api-s3cret", secret.Value())
+
+	close(release)
+	wg.Wait()
+	a.EqInt(1, int(atomic.LoadInt32(&calls)))
+	for _, r := range results {
+		a.EqStr("slow-s3cret", r)
+	}
+}
+
+func TestSecrets_ResolveTimeout(t *testing.T) {
+	a := assert.New(t)
+	source := keyvalue.StringKeyMap(map[string]interface{}{
+		"hung": "hung://key",
+	})
+	hung := resolverFunc(func(ctx context.Context, value string) (string, bool, error) {
+		<-ctx.Done()
+		return "", false, ctx.Err()
+	})
+	s := Secrets(source, hung).ResolveTimeout(10 * time.Millisecond)
+	_, err := s.Get("hung")
+	a.NotNil(err)
+	a.OK(!strings.Contains(err.Error(), "hung://key"), err.Error())
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SecretReferencePrefix is the prefix of the values resolved by SecretManager
+const SecretReferencePrefix = "secret://"
+
+// SecretManagerClient is an interface to access secrets in a secret manager service.
+// gcp/secretmanager.Client implements it for Google Cloud Secret Manager and StubSecretManager can be used locally.
+type SecretManagerClient interface {
+	AccessSecret(ctx context.Context, name string) ([]byte, error)
+}
+
+// SecretManager returns a SecretResolver to resolve `secret://{name}` values by `client`.
+func SecretManager(client SecretManagerClient) SecretResolver {
+	return &secretManager{
+		client: client,
+	}
+}
+
+type secretManager struct {
+	client SecretManagerClient
+}
+
+func (sm *secretManager) Resolve(ctx context.Context, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, SecretReferencePrefix) {
+		return "", false, nil
+	}
+	name := strings.TrimPrefix(value, SecretReferencePrefix)
+	if name == "" {
+		return "", true, fmt.Errorf("empty secret name")
+	}
+	secret, err := sm.client.AccessSecret(ctx, name)
+	if err != nil {
+		return "", true, fmt.Errorf("could not access the secret %q: %w", name, err)
+	}
+	return string(secret), true, nil
+}
+
+// StubSecretManager is a SecretManagerClient by a map for local environments and tests.
+type StubSecretManager map[string]string
+
+// AccessSecret implements SecretManagerClient#AccessSecret
+func (s StubSecretManager) AccessSecret(ctx context.Context, name string) ([]byte, error) {
+	v, ok := s[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %q is not found", name)
+	}
+	return []byte(v), nil
+}
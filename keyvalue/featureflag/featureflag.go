@@ -0,0 +1,223 @@
+// Package featureflag provides feature flags stored in a keyvalue.GetterSetter, such as kvstore.Store,
+// with percentage rollouts and user targeting.
+//
+//     flags := featureflag.New(kvstore.New(ctx, client, "FeatureFlag"))
+//     flags.Set(&featureflag.Flag{Name: "new_ui", Enabled: true, Percentage: 10, Users: []string{"admin"}})
+//     ...
+//     if flags.IsEnabled(featureflag.WithUser(ctx, userID), "new_ui") {
+//         ...
+//     }
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xcontext"
+	"github.com/yssk22/go/x/xlog"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "featureflag"
+
+// KeyPrefix is the prefix of the keys to store the flags
+const KeyPrefix = "featureflag."
+
+// Flag is a feature flag
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Enabled is the master switch. The flag is disabled for everyone if false.
+	Enabled bool `json:"enabled"`
+	// Percentage is the ratio of the users (0 - 100) that the flag is enabled for.
+	Percentage int `json:"percentage"`
+	// Users are the user IDs that the flag is always enabled for.
+	Users []string `json:"users,omitempty"`
+}
+
+// Validate validates the flag fields
+func (f *Flag) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("name must be specified")
+	}
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return fmt.Errorf("percentage must be in 0 - 100 but %d", f.Percentage)
+	}
+	return nil
+}
+
+// IsEnabledFor returns whether the flag is enabled for `user`.
+// The users in the rollout percentage are determined by the hash of the flag name and the user,
+// so the same user keeps the same result while the percentage is increased.
+// The anonymous user ("") is only in 100% rollouts.
+func (f *Flag) IsEnabledFor(user string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if f.Percentage >= 100 {
+		return true
+	}
+	if user == "" {
+		return false
+	}
+	for _, u := range f.Users {
+		if u == user {
+			return true
+		}
+	}
+	return bucket(f.Name, user) < f.Percentage
+}
+
+// bucket returns 0 - 99 for the user
+func bucket(name string, user string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(user))
+	return int(h.Sum32() % 100)
+}
+
+var userContextKey = xcontext.NewKey("user")
+
+// WithUser returns a new context with the user ID to evaluate the flags.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the user ID set by WithUser
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userContextKey).(string)
+	return user
+}
+
+// Option is a function to configure *Flags
+type Option func(*Flags) *Flags
+
+// UserFunc returns an Option to resolve the user ID from the context, e.g. from the session.
+// The default is UserFromContext.
+func UserFunc(f func(context.Context) string) Option {
+	return func(flags *Flags) *Flags {
+		flags.userFunc = f
+		return flags
+	}
+}
+
+// Flags is a set of feature flags
+type Flags struct {
+	store    keyvalue.GetterSetter
+	userFunc func(context.Context) string
+}
+
+// New returns a new *Flags stored in `store`
+func New(store keyvalue.GetterSetter, options ...Option) *Flags {
+	flags := &Flags{
+		store:    store,
+		userFunc: UserFromContext,
+	}
+	for _, f := range options {
+		flags = f(flags)
+	}
+	return flags
+}
+
+// IsEnabled returns whether the flag `name` is enabled for the user in the context.
+// It returns false if the flag does not exist or cannot be loaded.
+func (flags *Flags) IsEnabled(ctx context.Context, name string) bool {
+	f, err := flags.Get(name)
+	if err != nil {
+		if !keyvalue.IsKeyError(err) {
+			_, logger := xlog.WithContextAndKey(ctx, "", LoggerKey)
+			logger.Warnf("could not load the feature flag %q: %v", name, err)
+		}
+		return false
+	}
+	return f.IsEnabledFor(flags.userFunc(ctx))
+}
+
+// Get returns the flag for `name`. It returns keyvalue.KeyError if not found.
+func (flags *Flags) Get(name string) (*Flag, error) {
+	v, err := flags.store.Get(KeyPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := decode(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feature flag %q: %w", name, err)
+	}
+	f.Name = name
+	return f, nil
+}
+
+// Set stores the flag
+func (flags *Flags) Set(f *Flag) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	return flags.store.Set(KeyPrefix+f.Name, f)
+}
+
+// Delete deletes the flag for `name`. The store must support `Del(key)` like keyvalue.Map.
+func (flags *Flags) Delete(name string) error {
+	d, ok := flags.store.(interface {
+		Del(interface{}) error
+	})
+	if !ok {
+		return fmt.Errorf("%T does not support deletion", flags.store)
+	}
+	return d.Del(KeyPrefix + name)
+}
+
+// List returns all flags sorted by the name. The store must be keyvalue.Enumerable.
+func (flags *Flags) List() ([]*Flag, error) {
+	e, ok := flags.store.(keyvalue.Enumerable)
+	if !ok {
+		return nil, fmt.Errorf("%T is not keyvalue.Enumerable", flags.store)
+	}
+	var list []*Flag
+	for _, k := range e.Keys() {
+		key, ok := k.(string)
+		if !ok || !strings.HasPrefix(key, KeyPrefix) {
+			continue
+		}
+		f, err := flags.Get(strings.TrimPrefix(key, KeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// decode converts the stored value to *Flag. The value may be decoded from JSON by the store.
+func decode(v interface{}) (*Flag, error) {
+	switch f := v.(type) {
+	case *Flag:
+		copied := *f
+		return &copied, nil
+	case Flag:
+		return &f, nil
+	case string:
+		var decoded Flag
+		if err := json.Unmarshal([]byte(f), &decoded); err != nil {
+			return nil, err
+		}
+		return &decoded, nil
+	}
+	buff, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded Flag
+	if err := json.Unmarshal(buff, &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
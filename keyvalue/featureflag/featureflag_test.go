@@ -0,0 +1,70 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestFlag_IsEnabledFor(t *testing.T) {
+	a := assert.New(t)
+	f := &Flag{Name: "new_ui", Enabled: true, Percentage: 0, Users: []string{"admin"}}
+	a.OK(f.IsEnabledFor("admin"))
+	a.OK(!f.IsEnabledFor("user"))
+	a.OK(!f.IsEnabledFor(""))
+
+	f.Percentage = 30
+	var enabled []string
+	for i := 0; i < 1000; i++ {
+		if f.IsEnabledFor(fmt.Sprintf("user-%d", i)) {
+			enabled = append(enabled, fmt.Sprintf("user-%d", i))
+		}
+	}
+	a.OK(250 < len(enabled) && len(enabled) < 350, len(enabled))
+
+	// increasing the percentage keeps the enabled users
+	f.Percentage = 60
+	for _, user := range enabled {
+		a.OK(f.IsEnabledFor(user), user)
+	}
+
+	f.Percentage = 100
+	a.OK(f.IsEnabledFor(""))
+	f.Enabled = false
+	a.OK(!f.IsEnabledFor("admin"))
+}
+
+func TestFlags(t *testing.T) {
+	a := assert.New(t)
+	store := keyvalue.StringKeyMap(map[string]interface{}{
+		// as decoded from JSON by the store.
+		KeyPrefix + "beta": map[string]interface{}{"enabled": true, "users": []interface{}{"alice"}},
+		"other":            "value",
+	})
+	flags := New(store)
+	ctx := context.Background()
+	a.OK(flags.IsEnabled(WithUser(ctx, "alice"), "beta"))
+	a.OK(!flags.IsEnabled(WithUser(ctx, "bob"), "beta"))
+	a.OK(!flags.IsEnabled(ctx, "beta"))
+	a.OK(!flags.IsEnabled(ctx, "unknown"))
+
+	a.Nil(flags.Set(&Flag{Name: "alpha", Enabled: true, Percentage: 100}))
+	a.NotNil(flags.Set(&Flag{Name: "invalid", Percentage: 101}))
+	list, err := flags.List()
+	a.Nil(err)
+	a.EqInt(2, len(list))
+	a.EqStr("alpha", list[0].Name)
+	a.EqStr("beta", list[1].Name)
+
+	a.Nil(flags.Delete("alpha"))
+	_, err = flags.Get("alpha")
+	a.OK(keyvalue.IsKeyError(err))
+
+	flags = New(store, UserFunc(func(ctx context.Context) string {
+		return "alice"
+	}))
+	a.OK(flags.IsEnabled(ctx, "beta"))
+}
@@ -0,0 +1,80 @@
+package featureflag
+
+import (
+	"encoding/json"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/web"
+	"github.com/yssk22/go/web/api"
+	"github.com/yssk22/go/web/response"
+)
+
+// Mount mounts the admin API to view and edit the flags on `router` under `path`.
+//
+//     GET    {path}/       lists the flags
+//     GET    {path}/:name  returns the flag
+//     PUT    {path}/:name  creates or updates the flag by the JSON body
+//     DELETE {path}/:name  deletes the flag
+//
+// The routes are not protected so the access control should be done by middleware.
+func Mount(router web.Router, path string, flags *Flags) {
+	router.Get(path+"/", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		list, err := flags.List()
+		if err != nil {
+			return api.NewErrorResponse(req.Context(), err)
+		}
+		if list == nil {
+			list = []*Flag{}
+		}
+		return response.NewJSON(req.Context(), list)
+	}))
+	router.Get(path+"/:name", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		f, err := flags.Get(req.Params.GetStringOr("name", ""))
+		if err != nil {
+			if keyvalue.IsKeyError(err) {
+				return api.NotFound.ToResponse(req.Context())
+			}
+			return api.NewErrorResponse(req.Context(), err)
+		}
+		return response.NewJSON(req.Context(), f)
+	}))
+	router.Put(path+"/:name", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		var f Flag
+		if err := json.NewDecoder(req.Body).Decode(&f); err != nil {
+			return badRequest(req, err)
+		}
+		f.Name = req.Params.GetStringOr("name", "")
+		if err := f.Validate(); err != nil {
+			return badRequest(req, err)
+		}
+		if err := flags.Set(&f); err != nil {
+			return api.NewErrorResponse(req.Context(), err)
+		}
+		return response.NewJSON(req.Context(), &f)
+	}))
+	router.Delete(path+"/:name", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		if err := flags.Delete(req.Params.GetStringOr("name", "")); err != nil {
+			return api.NewErrorResponse(req.Context(), err)
+		}
+		return api.OK(req.Context())
+	}))
+}
+
+func badRequest(req *web.Request, err error) *response.Response {
+	return (&api.Error{
+		Code:    api.BadRequest.Code,
+		Message: err.Error(),
+		Status:  response.HTTPStatusBadRequest,
+	}).ToResponse(req.Context())
+}
+
+// UserMiddleware returns a web.Handler to set the user ID resolved by `f` (e.g. from the session)
+// to the request context so that IsEnabled can evaluate the flags for the user.
+func UserMiddleware(f func(*web.Request) string) web.Handler {
+	return web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		if user := f(req); user != "" {
+			return next(req.WithContext(WithUser(req.Context(), user)))
+		}
+		return next(req)
+	})
+}
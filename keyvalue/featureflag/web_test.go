@@ -0,0 +1,53 @@
+package featureflag
+
+import (
+	"testing"
+
+	"github.com/yssk22/go/keyvalue"
+	"github.com/yssk22/go/web"
+	"github.com/yssk22/go/web/httptest"
+	"github.com/yssk22/go/web/response"
+)
+
+func TestMount(t *testing.T) {
+	a := httptest.NewAssert(t)
+	flags := New(keyvalue.NewStringKeyMap())
+	router := web.NewRouter(nil)
+	router.Use(UserMiddleware(func(req *web.Request) string {
+		return req.Query.GetStringOr("user", "")
+	}))
+	Mount(router, "/admin/flags", flags)
+	router.Get("/check", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return response.NewText(req.Context(), flags.IsEnabled(req.Context(), "beta"))
+	}))
+	recorder := httptest.NewRecorder(router)
+
+	res := recorder.TestGet("/admin/flags/beta")
+	a.Status(response.HTTPStatusNotFound, res)
+
+	res = recorder.TestPut("/admin/flags/beta", map[string]interface{}{
+		"enabled":    true,
+		"percentage": 200,
+	})
+	a.Status(response.HTTPStatusBadRequest, res)
+
+	res = recorder.TestPut("/admin/flags/beta", map[string]interface{}{
+		"enabled": true,
+		"users":   []string{"alice"},
+	})
+	a.Status(response.HTTPStatusOK, res)
+
+	var list []*Flag
+	res = recorder.TestGet("/admin/flags/")
+	a.Status(response.HTTPStatusOK, res)
+	a.JSON(&list, res)
+	a.EqInt(1, len(list))
+	a.EqStr("beta", list[0].Name)
+
+	a.Body("true", recorder.TestGet("/check?user=alice"))
+	a.Body("false", recorder.TestGet("/check?user=bob"))
+
+	res = recorder.TestDelete("/admin/flags/beta")
+	a.Status(response.HTTPStatusOK, res)
+	a.Body("false", recorder.TestGet("/check?user=alice"))
+}
@@ -6,8 +6,6 @@ import (
 	"time"
 
 	"github.com/yssk22/go/x/xtime"
-
-	"github.com/yssk22/go/number"
 )
 
 // Getter is an interface to get a value by a key
@@ -45,88 +43,45 @@ func GetOr(g Getter, key interface{}, or interface{}) interface{} {
 		return or
 	}
 	v, e := g.Get(key)
-	if e != nil {
+	if e != nil || v == nil {
 		return or
 	}
-	return fmt.Sprintf("%s", v)
+	return v
 }
 
 // GetStringOr is string version of GetOr.
+// If the value is a []string, the first element is used.
 func GetStringOr(g Getter, key interface{}, or string) string {
-	if g == nil {
-		return or
-	}
-	v, e := g.Get(key)
-	if e != nil {
-		return or
-	}
-	switch v.(type) {
-	case []string:
-		// Take the first element follwoing to url.Values implementation
-		l := v.([]string)
-		if len(l) > 0 {
-			return l[0]
-		}
+	v, err := GetString(g, key)
+	if err != nil {
+		orFallback(err)
 		return or
-	default:
-		return fmt.Sprintf("%s", v)
 	}
+	return v
 }
 
-// GetIntOr is int version of GetOr.
+// GetIntOr is int version of GetOr. It returns `or` if the value cannot be converted (use GetInt to get the error).
 func GetIntOr(g Getter, key interface{}, or int) int {
-	if g == nil {
-		return or
-	}
-	v, e := g.Get(key)
-	if e != nil {
-		return or
-	}
-	switch v.(type) {
-	case int8:
-		return int(v.(int8))
-	case int16:
-		return int(v.(int16))
-	case int32:
-		return int(v.(int32))
-	case int64:
-		return int(v.(int64))
-	case int:
-		return v.(int)
-	case string:
-		return number.ParseIntOr(v.(string), or)
-	default:
+	v, err := GetInt(g, key)
+	if err != nil {
+		orFallback(err)
 		return or
 	}
+	return v
 }
 
-// GetFloatOr is float64 version of GetOr.
+// GetFloatOr is float64 version of GetOr. It returns `or` if the value cannot be converted (use GetFloat to get the error).
 func GetFloatOr(g Getter, key interface{}, or float64) float64 {
-	if g == nil {
-		return or
-	}
-	v, e := g.Get(key)
-	if e != nil {
-		return or
-	}
-	switch v.(type) {
-	case int8:
-		return float64(v.(int8))
-	case int16:
-		return float64(v.(int16))
-	case int32:
-		return float64(v.(int32))
-	case int64:
-		return float64(v.(int64))
-	case int:
-		return float64(v.(int))
-	case string:
-		return number.ParseFloat64Or(v.(string), or)
-	default:
+	v, err := GetFloat(g, key)
+	if err != nil {
+		orFallback(err)
 		return or
 	}
+	return v
 }
 
+// GetDateOr is time.Time version of GetOr. The value is parsed as YYYY/MM/DD in the location of `or`
+// and the year of `or` is used if omitted.
 func GetDateOr(g Getter, key interface{}, or time.Time) time.Time {
 	if g == nil {
 		return or
@@ -141,7 +96,10 @@ func GetDateOr(g Getter, key interface{}, or time.Time) time.Time {
 		if e == nil {
 			return t
 		}
+		orFallback(&ValueError{Key: key, Value: v, Err: e})
 		return or
+	case time.Time:
+		return v.(time.Time)
 	default:
 		return or
 	}
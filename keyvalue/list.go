@@ -1,11 +1,20 @@
 package keyvalue
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // List is a list of key-value store.
+// It is safe to Get while the Watchable members are reloaded by Reload.
 type List struct {
 	list []Getter
 	*GetProxy
+
+	mu       sync.RWMutex
+	reloadMu sync.Mutex
+	watchMu  sync.Mutex
+	watchers []*watcher
 }
 
 // NewList returns a new *List for `g`
@@ -24,6 +33,8 @@ func NewList(g ...Getter) *List {
 //   - If a Getter item returns KeyError, it tries the next Getter item.
 //
 func (l *List) Get(key interface{}) (interface{}, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	for _, getter := range l.list {
 		v, e := getter.Get(key)
 		if e != nil {
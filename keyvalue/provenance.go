@@ -0,0 +1,99 @@
+package keyvalue
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Provenance describes which member of a *List supplied the value.
+type Provenance struct {
+	// Index is the index of the member in the List.
+	Index int
+	// Source is the Getter that supplied the value. If the member is a *List, it is the member of that List.
+	Source Getter
+}
+
+func (p *Provenance) String() string {
+	if s, ok := p.Source.(fmt.Stringer); ok {
+		return fmt.Sprintf("#%d %s", p.Index, s)
+	}
+	return fmt.Sprintf("#%d %T", p.Index, p.Source)
+}
+
+// Lookup is like Get but also returns the Provenance of the value.
+func (l *List) Lookup(key interface{}) (interface{}, *Provenance, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i, getter := range l.list {
+		if nested, ok := getter.(*List); ok {
+			v, p, e := nested.Lookup(key)
+			if e != nil && !IsKeyError(e) {
+				return nil, nil, e
+			}
+			if v != nil {
+				return v, &Provenance{Index: i, Source: p.Source}, nil
+			}
+			continue
+		}
+		v, e := getter.Get(key)
+		if e != nil && !IsKeyError(e) {
+			return nil, nil, e
+		}
+		if v != nil {
+			return v, &Provenance{Index: i, Source: getter}, nil
+		}
+	}
+	return nil, nil, KeyError(fmt.Sprintf("%s", key))
+}
+
+// Enumerable is an extension interface for Getter that can list its keys.
+type Enumerable interface {
+	Getter
+	Keys() []interface{}
+}
+
+// Keys implements Enumerable#Keys for Map
+func (m Map) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return sortKeys(keys)
+}
+
+// Keys implements Enumerable#Keys for StringKeyMap
+func (m StringKeyMap) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return sortKeys(keys)
+}
+
+// Keys implements Enumerable#Keys and returns the union of the keys of Enumerable members.
+func (l *List) Keys() []interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	seen := make(map[interface{}]bool)
+	var keys []interface{}
+	for _, getter := range l.list {
+		e, ok := getter.(Enumerable)
+		if !ok {
+			continue
+		}
+		for _, k := range e.Keys() {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return sortKeys(keys)
+}
+
+func sortKeys(keys []interface{}) []interface{} {
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
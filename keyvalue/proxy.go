@@ -72,3 +72,43 @@ func (p *GetProxy) GetDateOr(key string, or time.Time) time.Time {
 	}
 	return GetDateOr(p.g, key, or)
 }
+
+// GetString is shorthand for keyvalue.GetString.
+func (p *GetProxy) GetString(key string) (string, error) {
+	return GetString(p.g, key)
+}
+
+// GetInt is shorthand for keyvalue.GetInt.
+func (p *GetProxy) GetInt(key string) (int, error) {
+	return GetInt(p.g, key)
+}
+
+// GetFloat is shorthand for keyvalue.GetFloat.
+func (p *GetProxy) GetFloat(key string) (float64, error) {
+	return GetFloat(p.g, key)
+}
+
+// GetBool is shorthand for keyvalue.GetBool.
+func (p *GetProxy) GetBool(key string) (bool, error) {
+	return GetBool(p.g, key)
+}
+
+// GetDuration is shorthand for keyvalue.GetDuration.
+func (p *GetProxy) GetDuration(key string) (time.Duration, error) {
+	return GetDuration(p.g, key)
+}
+
+// GetStringSlice is shorthand for keyvalue.GetStringSlice.
+func (p *GetProxy) GetStringSlice(key string) ([]string, error) {
+	return GetStringSlice(p.g, key)
+}
+
+// GetTime is shorthand for keyvalue.GetTime.
+func (p *GetProxy) GetTime(key string) (time.Time, error) {
+	return GetTime(p.g, key)
+}
+
+// GetSecret is shorthand for keyvalue.GetSecret.
+func (p *GetProxy) GetSecret(key string) (Secret, error) {
+	return GetSecret(p.g, key)
+}
@@ -0,0 +1,47 @@
+package keyvalue
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Redacted is a string shown instead of secret values.
+const Redacted = "[REDACTED]"
+
+var secretType = reflect.TypeOf(Secret{})
+
+// Secret is a string value that must not be shown in logs or dumps.
+// fmt and encoding/json print Redacted and the value is only available by Value().
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a new Secret for `value`
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// Value returns the secret value
+func (s Secret) Value() string {
+	return s.value
+}
+
+// IsZero returns whether the secret is empty
+func (s Secret) IsZero() bool {
+	return s.value == ""
+}
+
+// String implements fmt.Stringer#String and returns Redacted.
+func (s Secret) String() string {
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer#GoString for "%#v".
+func (s Secret) GoString() string {
+	return "keyvalue.Secret(" + Redacted + ")"
+}
+
+// MarshalJSON implements json.Marshaler#MarshalJSON
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
@@ -0,0 +1,64 @@
+package keyvalue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestSecret(t *testing.T) {
+	a := assert.New(t)
+	s := NewSecret("p@ssw0rd")
+	a.EqStr("p@ssw0rd", s.Value())
+	for _, str := range []string{
+		fmt.Sprint(s),
+		fmt.Sprintf("%v %s %+v %#v", s, s, s, s),
+		fmt.Sprintf("%v", struct{ Password Secret }{s}),
+	} {
+		a.OK(!strings.Contains(str, "p@ssw0rd"), str)
+		a.OK(strings.Contains(str, Redacted), str)
+	}
+	b, err := json.Marshal(map[string]interface{}{"password": s})
+	a.Nil(err)
+	a.EqStr(`{"password":"[REDACTED]"}`, string(b))
+
+	m := StringKeyMap(map[string]interface{}{
+		"password": s,
+		"plain":    "text",
+	})
+	a.EqStr(Redacted, fmt.Sprint(GetOr(m, "password", nil)))
+	secret, err := GetSecret(m, "plain")
+	a.Nil(err)
+	a.EqStr("text", secret.Value())
+
+	var cfg struct {
+		Password Secret `config:"password"`
+		Plain    Secret `config:"plain"`
+		Revealed string `config:"password"`
+	}
+	a.Nil(Bind(m, &cfg))
+	a.EqStr("p@ssw0rd", cfg.Password.Value())
+	a.EqStr("text", cfg.Plain.Value())
+	a.EqStr("p@ssw0rd", cfg.Revealed)
+}
+
+func TestSecret_convertError(t *testing.T) {
+	a := assert.New(t)
+	m := StringKeyMap(map[string]interface{}{
+		"db.password": NewSecret("p@ssw0rd"),
+	})
+	_, err := GetInt(m, "db.password")
+	a.NotNil(err)
+	a.OK(!strings.Contains(err.Error(), "p@ssw0rd"), err.Error())
+	a.OK(strings.Contains(err.Error(), Redacted), err.Error())
+
+	var cfg struct {
+		Password bool `config:"db.password"`
+	}
+	err = Bind(m, &cfg)
+	a.NotNil(err)
+	a.OK(!strings.Contains(err.Error(), "p@ssw0rd"), err.Error())
+}
@@ -0,0 +1,106 @@
+package keyvalue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/yssk22/go/x/xlog"
+)
+
+// ValueError is the error when the value cannot be converted to the requested type.
+type ValueError struct {
+	Key   interface{}
+	Value interface{}
+	Err   error
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("key %q: %v", fmt.Sprint(e.Key), e.Err)
+}
+
+// Unwrap returns the cause
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// GetString gets a value as string. Numbers and bools are formatted. It returns KeyError if not found.
+func GetString(g Getter, key interface{}) (string, error) {
+	var s string
+	return s, getAs(g, key, &s)
+}
+
+// GetInt gets a value as int. Strings are parsed. It returns KeyError if not found or *ValueError if it cannot be converted.
+func GetInt(g Getter, key interface{}) (int, error) {
+	var i int
+	return i, getAs(g, key, &i)
+}
+
+// GetFloat is a float64 version of GetInt.
+func GetFloat(g Getter, key interface{}) (float64, error) {
+	var f float64
+	return f, getAs(g, key, &f)
+}
+
+// GetBool is a bool version of GetInt. Strings are parsed by strconv.ParseBool.
+func GetBool(g Getter, key interface{}) (bool, error) {
+	var b bool
+	return b, getAs(g, key, &b)
+}
+
+// GetDuration is a time.Duration version of GetInt. Strings are parsed by time.ParseDuration.
+func GetDuration(g Getter, key interface{}) (time.Duration, error) {
+	var d time.Duration
+	return d, getAs(g, key, &d)
+}
+
+// GetStringSlice is a []string version of GetInt. A string value is split by ','.
+func GetStringSlice(g Getter, key interface{}) ([]string, error) {
+	var s []string
+	return s, getAs(g, key, &s)
+}
+
+// GetTime is a time.Time version of GetInt. Strings are parsed as RFC3339 or YYYY/MM/DD by xtime.
+func GetTime(g Getter, key interface{}) (time.Time, error) {
+	var t time.Time
+	return t, getAs(g, key, &t)
+}
+
+// GetSecret gets a value as Secret. Strings are wrapped so that the value is not shown in logs.
+func GetSecret(g Getter, key interface{}) (Secret, error) {
+	var s Secret
+	return s, getAs(g, key, &s)
+}
+
+func getAs(g Getter, key interface{}, dst interface{}) error {
+	if g == nil {
+		return KeyError(fmt.Sprint(key))
+	}
+	value, err := g.Get(key)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return KeyError(fmt.Sprint(key))
+	}
+	v := reflect.ValueOf(dst).Elem()
+	converted, err := convert(value, v.Type())
+	if err != nil {
+		return &ValueError{
+			Key:   key,
+			Value: value,
+			Err:   err,
+		}
+	}
+	v.Set(converted)
+	return nil
+}
+
+// orFallback logs the conversion error since the Get*Or functions return the default values for invalid values.
+func orFallback(err error) {
+	if _, ok := err.(*ValueError); ok {
+		_, logger := xlog.WithContextAndKey(context.Background(), "keyvalue", LoggerKey)
+		logger.Debugf("fallback to the default value: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package keyvalue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestGetTyped(t *testing.T) {
+	a := assert.New(t)
+	m := Map{
+		"int":      "10",
+		"float":    float32(1.5),
+		"bool":     "true",
+		"duration": "1m",
+		"slice":    "a,b",
+		"time":     "2020-01-02T03:04:05Z",
+		"invalid":  "foo",
+	}
+	i, err := GetInt(m, "int")
+	a.Nil(err)
+	a.EqInt(10, i)
+	f, err := GetFloat(m, "float")
+	a.Nil(err)
+	a.OK(f == 1.5)
+	b, err := GetBool(m, "bool")
+	a.Nil(err)
+	a.OK(b)
+	d, err := GetDuration(m, "duration")
+	a.Nil(err)
+	a.OK(d == time.Minute)
+	s, err := GetStringSlice(m, "slice")
+	a.Nil(err)
+	a.EqInt(2, len(s))
+	tt, err := GetTime(m, "time")
+	a.Nil(err)
+	a.EqInt(2020, tt.Year())
+
+	_, err = GetInt(m, "invalid")
+	a.NotNil(err)
+	ve, ok := err.(*ValueError)
+	a.OK(ok)
+	a.EqStr("foo", ve.Value.(string))
+	_, err = GetBool(m, "missing")
+	a.OK(IsKeyError(err))
+}
+
+func TestGetOrFixes(t *testing.T) {
+	a := assert.New(t)
+	m := Map{
+		"int":     1,
+		"float64": 1.5,
+		"float32": float32(2.5),
+	}
+	a.EqInt(1, GetOr(m, "int", 0).(int))
+	a.EqStr("1", GetStringOr(m, "int", ""))
+	a.OK(GetFloatOr(m, "float64", 0) == 1.5)
+	a.OK(GetFloatOr(m, "float32", 0) == 2.5)
+}
+
+func TestList_Lookup(t *testing.T) {
+	a := assert.New(t)
+	m1 := Map{"a": "1"}
+	m2 := Map{"a": "2", "b": "2"}
+	list := NewList(m1, NewList(NewMap(), m2))
+	v, p, err := list.Lookup("b")
+	a.Nil(err)
+	a.EqStr("2", v.(string))
+	a.EqInt(1, p.Index)
+	a.OK(p.Source.(Map)["b"] == "2")
+	_, _, err = list.Lookup("c")
+	a.OK(IsKeyError(err))
+
+	keys := list.Keys()
+	a.EqInt(2, len(keys))
+	a.EqStr("a", keys[0].(string))
+}
@@ -0,0 +1,130 @@
+package keyvalue
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xlog"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "keyvalue"
+
+// Watchable is an extension interface for Getter whose values can be changed, such as configuration files.
+//
+// Poll checks the changes and prepares the new values without affecting Get. If changed, it returns
+// a function to apply them, which must be fast and not fail so that *List can apply the changes of
+// all members at once. If nothing is changed, it returns nil.
+type Watchable interface {
+	Getter
+	Poll() (apply func(), err error)
+}
+
+type watcher struct {
+	key interface{}
+	f   func(old, new interface{})
+}
+
+// Watch registers `f` to be called when the value for `key` is changed by Reload.
+// `old` or `new` is nil if the value is not found. It returns a function to unregister.
+func (l *List) Watch(key interface{}, f func(old, new interface{})) func() {
+	l.watchMu.Lock()
+	defer l.watchMu.Unlock()
+	w := &watcher{
+		key: key,
+		f:   f,
+	}
+	l.watchers = append(l.watchers, w)
+	return func() {
+		l.watchMu.Lock()
+		defer l.watchMu.Unlock()
+		for i, ww := range l.watchers {
+			if ww == w {
+				l.watchers = append(l.watchers[:i], l.watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Poll implements Watchable#Poll so that a *List can be a member of another *List.
+// The changes of the members are applied at once while Get is blocked.
+func (l *List) Poll() (func(), error) {
+	var applies []func()
+	var errors xerrors.MultiError
+	for _, g := range l.list {
+		w, ok := g.(Watchable)
+		if !ok {
+			continue
+		}
+		apply, err := w.Poll()
+		if err != nil {
+			errors = append(errors, err)
+		}
+		if apply != nil {
+			applies = append(applies, apply)
+		}
+	}
+	if len(applies) == 0 {
+		return nil, errors.ToReturn()
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, apply := range applies {
+			apply()
+		}
+	}, errors.ToReturn()
+}
+
+// Reload polls the Watchable members and applies the changes at once, then notifies the watchers
+// whose values are changed. The members failed to reload keep the current values and the errors are returned.
+func (l *List) Reload() error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	apply, err := l.Poll()
+	if apply == nil {
+		return err
+	}
+	l.watchMu.Lock()
+	watchers := make([]*watcher, len(l.watchers))
+	copy(watchers, l.watchers)
+	l.watchMu.Unlock()
+	olds := make([]interface{}, len(watchers))
+	for i, w := range watchers {
+		olds[i], _ = l.Get(w.key)
+	}
+	apply()
+	for i, w := range watchers {
+		v, _ := l.Get(w.key)
+		if !reflect.DeepEqual(olds[i], v) {
+			w.f(olds[i], v)
+		}
+	}
+	return err
+}
+
+// StartPolling calls Reload every `interval` until the returned function is called.
+func (l *List) StartPolling(interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Reload(); err != nil {
+					_, logger := xlog.WithContextAndKey(context.Background(), "keyvalue", LoggerKey)
+					logger.Warnf("could not reload the values: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,105 @@
+package keyvalue
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+// watchableMap is a Watchable that applies `next` on Poll
+type watchableMap struct {
+	mu   sync.RWMutex
+	m    Map
+	next Map
+	err  error
+}
+
+func (w *watchableMap) Get(key interface{}) (interface{}, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.m.Get(key)
+}
+
+func (w *watchableMap) Poll() (func(), error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.next == nil {
+		return nil, nil
+	}
+	next := w.next
+	w.next = nil
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.m = next
+	}, nil
+}
+
+func TestList_Reload(t *testing.T) {
+	a := assert.New(t)
+	m1 := &watchableMap{m: Map{"a": "1"}}
+	m2 := &watchableMap{m: Map{"a": "2", "b": "2"}}
+	list := NewList(m1, NewList(m2))
+	var changes []string
+	cancel := list.Watch("b", func(old, new interface{}) {
+		changes = append(changes, fmt.Sprintf("%v -> %v", old, new))
+	})
+	list.Watch("a", func(old, new interface{}) {
+		changes = append(changes, fmt.Sprintf("%v -> %v", old, new))
+	})
+	a.Nil(list.Reload())
+	a.EqInt(0, len(changes))
+
+	m1.next = Map{}
+	m2.next = Map{"a": "3", "b": "3"}
+	a.Nil(list.Reload())
+	a.EqInt(2, len(changes))
+	a.EqStr("2 -> 3", changes[0])
+	a.EqStr("1 -> 3", changes[1])
+
+	cancel()
+	m2.next = Map{"a": "3", "b": "4"}
+	a.Nil(list.Reload())
+	a.EqInt(2, len(changes))
+	a.EqStr("4", list.GetStringOr("b", ""))
+
+	m1.err = fmt.Errorf("error")
+	m2.next = Map{"a": "5"}
+	a.NotNil(list.Reload())
+	a.EqStr("5", list.GetStringOr("a", ""))
+}
+
+func TestList_ReloadConcurrently(t *testing.T) {
+	a := assert.New(t)
+	m1 := &watchableMap{m: Map{"a": "1"}}
+	m2 := &watchableMap{m: Map{"a": "1"}}
+	list := NewList(m1, m2)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				a.OK(list.GetStringOr("a", "") != "")
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		// "a" is always found in either of them.
+		m1.next = Map{}
+		m2.next = Map{"a": fmt.Sprint(i)}
+		list.Reload()
+		m1.next = Map{"a": fmt.Sprint(i)}
+		m2.next = Map{}
+		list.Reload()
+	}
+	close(done)
+	wg.Wait()
+}
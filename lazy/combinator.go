@@ -0,0 +1,149 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/yssk22/go/x/xerrors"
+)
+
+// Map returns a Value that converts the result of `v` by `f`. `f` is not called if `v` fails.
+func Map(v Value, f func(interface{}) (interface{}, error)) Value {
+	return Func(func(ctx context.Context) (interface{}, error) {
+		value, err := v.Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return f(value)
+	})
+}
+
+// Then returns a Value that evaluates the Value returned by `f` with the result of `v`.
+// This is useful when a lookup depends on another lazy value.
+func Then(v Value, f func(context.Context, interface{}) Value) Value {
+	return Func(func(ctx context.Context) (interface{}, error) {
+		value, err := v.Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return f(ctx, value).Eval(ctx)
+	})
+}
+
+// Catch returns a Value that recovers the error of `v` by `f`.
+func Catch(v Value, f func(context.Context, error) (interface{}, error)) Value {
+	return Func(func(ctx context.Context) (interface{}, error) {
+		value, err := v.Eval(ctx)
+		if err != nil {
+			return f(ctx, err)
+		}
+		return value, nil
+	})
+}
+
+// Fallback returns a Value that evaluates `fallback` if `v` fails.
+func Fallback(v Value, fallback Value) Value {
+	return Catch(v, func(ctx context.Context, _ error) (interface{}, error) {
+		return fallback.Eval(ctx)
+	})
+}
+
+// All evaluates `values` concurrently and returns the results in the same order.
+// The errors are returned as xerrors.MultiError and the values not evaluated before ctx is done get ctx.Err().
+func All(ctx context.Context, values ...Value) ([]interface{}, error) {
+	type result struct {
+		index int
+		value interface{}
+		err   error
+	}
+	results := make([]interface{}, len(values))
+	errors := xerrors.NewMultiError(len(values))
+	done := make([]bool, len(values))
+	ch := make(chan *result, len(values))
+	for i, v := range values {
+		go func(i int, v Value) {
+			value, err := v.Eval(ctx)
+			ch <- &result{
+				index: i,
+				value: value,
+				err:   err,
+			}
+		}(i, v)
+	}
+	for range values {
+		select {
+		case r := <-ch:
+			results[r.index] = r.value
+			errors[r.index] = r.err
+			done[r.index] = true
+		case <-ctx.Done():
+			for i := range values {
+				if !done[i] {
+					errors[i] = ctx.Err()
+				}
+			}
+			return results, errors
+		}
+	}
+	return results, errors.ToReturn()
+}
+
+// Resolve evaluates `values` by All and sets the results to the fields of the struct pointed by `dst`.
+// The field is matched by the `lazy` tag or the field name.
+func Resolve(ctx context.Context, dst interface{}, values map[string]Value) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dst must be a pointer to struct but %T", dst)
+	}
+	v = v.Elem()
+	var fields []reflect.Value
+	var names []string
+	var lazyValues []Value
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("lazy")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if lv, ok := values[name]; ok {
+			fields = append(fields, v.Field(i))
+			names = append(names, name)
+			lazyValues = append(lazyValues, lv)
+		}
+	}
+	results, err := All(ctx, lazyValues...)
+	errors, _ := err.(xerrors.MultiError)
+	if err != nil && errors == nil {
+		return err
+	}
+	if errors == nil {
+		errors = xerrors.NewMultiError(len(results))
+	}
+	for i, result := range results {
+		if errors[i] != nil {
+			errors[i] = xerrors.Wrap(errors[i], "could not resolve %s", names[i])
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		rv := reflect.ValueOf(result)
+		switch {
+		case rv.Type().AssignableTo(fields[i].Type()):
+			fields[i].Set(rv)
+		case rv.Kind() == fields[i].Kind() && rv.Type().ConvertibleTo(fields[i].Type()):
+			fields[i].Set(rv.Convert(fields[i].Type()))
+		default:
+			errors[i] = fmt.Errorf("could not resolve %s: %T is not assignable to %s", names[i], result, fields[i].Type())
+		}
+	}
+	return errors.ToReturn()
+}
@@ -0,0 +1,131 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func failure(msg string) Value {
+	return Func(func(context.Context) (interface{}, error) {
+		return nil, fmt.Errorf(msg)
+	})
+}
+
+func TestMapAndThen(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	v, err := Map(New(1), func(v interface{}) (interface{}, error) {
+		return v.(int) * 2, nil
+	}).Eval(ctx)
+	a.Nil(err)
+	a.EqInt(2, v.(int))
+
+	v, err = Then(New("foo"), func(ctx context.Context, v interface{}) Value {
+		return New(v.(string) + "bar")
+	}).Eval(ctx)
+	a.Nil(err)
+	a.EqStr("foobar", v.(string))
+
+	_, err = Map(failure("error"), func(v interface{}) (interface{}, error) {
+		panic("should not be called")
+	}).Eval(ctx)
+	a.NotNil(err)
+}
+
+func TestCatchAndFallback(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	v, err := Catch(failure("error"), func(ctx context.Context, err error) (interface{}, error) {
+		return err.Error(), nil
+	}).Eval(ctx)
+	a.Nil(err)
+	a.EqStr("error", v.(string))
+
+	v, err = Fallback(failure("error"), New("fallback")).Eval(ctx)
+	a.Nil(err)
+	a.EqStr("fallback", v.(string))
+
+	v, err = Fallback(New("value"), New("fallback")).Eval(ctx)
+	a.Nil(err)
+	a.EqStr("value", v.(string))
+}
+
+func TestAll(t *testing.T) {
+	a := assert.New(t)
+	start := make(chan struct{})
+	wait := func(v interface{}) Value {
+		return Func(func(context.Context) (interface{}, error) {
+			<-start
+			return v, nil
+		})
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(start)
+	}()
+	results, err := All(context.Background(), wait(1), wait(2), failure("error"))
+	a.NotNil(err)
+	errors := err.(xerrors.MultiError)
+	a.Nil(errors[0])
+	a.Nil(errors[1])
+	a.EqStr("error", errors[2].Error())
+	a.EqInt(1, results[0].(int))
+	a.EqInt(2, results[1].(int))
+
+	results, err = All(context.Background(), New(1), New(2))
+	a.Nil(err)
+	a.EqInt(2, len(results))
+}
+
+func TestAll_Cancel(t *testing.T) {
+	a := assert.New(t)
+	block := make(chan struct{})
+	defer close(block)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	results, err := All(ctx, New(1), Func(func(context.Context) (interface{}, error) {
+		<-block
+		return 2, nil
+	}))
+	a.NotNil(err)
+	errors := err.(xerrors.MultiError)
+	a.OK(errors[1] == context.DeadlineExceeded)
+	a.Nil(results[1])
+}
+
+func TestResolve(t *testing.T) {
+	a := assert.New(t)
+	type myInt int
+	var dst struct {
+		Name   string
+		Count  myInt  `lazy:"count"`
+		Ignore string `lazy:"-"`
+		Other  string
+	}
+	err := Resolve(context.Background(), &dst, map[string]Value{
+		"Name":   New("foo"),
+		"count":  New(10),
+		"Ignore": New("ignore"),
+	})
+	a.Nil(err)
+	a.EqStr("foo", dst.Name)
+	a.EqInt(10, int(dst.Count))
+	a.EqStr("", dst.Ignore)
+
+	err = Resolve(context.Background(), &dst, map[string]Value{
+		"Name":  New(1),
+		"Other": failure("error"),
+	})
+	a.NotNil(err)
+	errors := err.(xerrors.MultiError)
+	a.EqInt(2, len(errors))
+	a.NotNil(errors[0])
+	a.NotNil(errors[1])
+
+	a.NotNil(Resolve(context.Background(), dst, nil))
+}
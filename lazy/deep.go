@@ -0,0 +1,227 @@
+package lazy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/yssk22/go/x/xerrors"
+)
+
+// MaxDepth is the maximum depth of the data graph that EvalDeep walks into.
+var MaxDepth = 64
+
+// Evaluated is a Value that holds an evaluated result.
+// EvalDeep uses it to replace a Value where the result itself cannot be set, such as a struct field typed as Value.
+type Evaluated struct {
+	Value interface{}
+}
+
+// Eval implements Value#Eval
+func (e Evaluated) Eval(context.Context) (interface{}, error) {
+	return e.Value, nil
+}
+
+// MarshalJSON implements json.Marshaler to marshal the result as it is.
+func (e Evaluated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Value)
+}
+
+func (e Evaluated) String() string {
+	return fmt.Sprint(e.Value)
+}
+
+var valueType = reflect.TypeOf((*Value)(nil)).Elem()
+
+// EvalDeep returns a copy of `v` where Values in maps, slices, arrays, struct fields and pointers are replaced by their results.
+// Values in the same container are evaluated concurrently. The parts without Values are shared with `v` and not copied.
+func EvalDeep(ctx context.Context, v interface{}) (interface{}, error) {
+	rv, err := evalDeep(ctx, reflect.ValueOf(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	return rv.Interface(), nil
+}
+
+func evalDeep(ctx context.Context, v reflect.Value, depth int) (reflect.Value, error) {
+	if depth > MaxDepth {
+		return v, fmt.Errorf("lazy: too deep data graph (> %d)", MaxDepth)
+	}
+	if !v.IsValid() {
+		return v, nil
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, nil
+		}
+		return evalDeep(ctx, v.Elem(), depth)
+	}
+	if isValue(v) {
+		result, err := v.Interface().(Value).Eval(ctx)
+		if err != nil {
+			return v, err
+		}
+		return evalDeep(ctx, reflect.ValueOf(result), depth+1)
+	}
+	if !hasValue(v, depth) {
+		return v, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem, err := evalDeep(ctx, v.Elem(), depth+1)
+		if err != nil {
+			return v, err
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := set(p.Elem(), elem); err != nil {
+			return v, err
+		}
+		return p, nil
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		var slots []reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				slots = append(slots, copied.Field(i))
+			}
+		}
+		return copied, evalSlots(ctx, slots, depth)
+	case reflect.Slice:
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(copied, v)
+		return copied, evalSlots(ctx, sliceSlots(copied), depth)
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		return copied, evalSlots(ctx, sliceSlots(copied), depth)
+	case reflect.Map:
+		keys := v.MapKeys()
+		slots := make([]reflect.Value, len(keys))
+		for i, k := range keys {
+			slots[i] = reflect.New(v.Type().Elem()).Elem()
+			slots[i].Set(v.MapIndex(k))
+		}
+		if err := evalSlots(ctx, slots, depth); err != nil {
+			return v, err
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), len(keys))
+		for i, k := range keys {
+			copied.SetMapIndex(k, slots[i])
+		}
+		return copied, nil
+	}
+	return v, nil
+}
+
+// evalSlots evaluates the settable values in `slots` concurrently and sets the results back.
+func evalSlots(ctx context.Context, slots []reflect.Value, depth int) error {
+	var values []Value
+	var targets []reflect.Value
+	for _, slot := range slots {
+		if isValue(slot) || hasValue(slot, depth+1) {
+			slot := slot
+			targets = append(targets, slot)
+			values = append(values, Func(func(ctx context.Context) (interface{}, error) {
+				return evalDeep(ctx, slot, depth+1)
+			}))
+		}
+	}
+	results, err := All(ctx, values...)
+	if err != nil {
+		for _, e := range err.(xerrors.MultiError) {
+			if e != nil {
+				return e
+			}
+		}
+	}
+	for i, target := range targets {
+		if err := set(target, results[i].(reflect.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sliceSlots(v reflect.Value) []reflect.Value {
+	slots := make([]reflect.Value, v.Len())
+	for i := range slots {
+		slots[i] = v.Index(i)
+	}
+	return slots
+}
+
+// set sets the result to the slot. If the result is not assignable but the slot can hold a Value, it is wrapped by Evaluated.
+func set(slot reflect.Value, result reflect.Value) error {
+	if !result.IsValid() {
+		slot.Set(reflect.Zero(slot.Type()))
+		return nil
+	}
+	if result.Type().AssignableTo(slot.Type()) {
+		slot.Set(result)
+		return nil
+	}
+	evaluated := reflect.ValueOf(Evaluated{result.Interface()})
+	if evaluated.Type().AssignableTo(slot.Type()) {
+		slot.Set(evaluated)
+		return nil
+	}
+	return fmt.Errorf("lazy: could not set %s to %s", result.Type(), slot.Type())
+}
+
+func isValue(v reflect.Value) bool {
+	if !v.IsValid() || !v.CanInterface() {
+		return false
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if _, ok := v.Interface().(Evaluated); ok {
+		return false
+	}
+	return v.Type().Implements(valueType)
+}
+
+// hasValue returns true if `v` contains a Value in its graph.
+func hasValue(v reflect.Value, depth int) bool {
+	if depth > MaxDepth || !v.IsValid() {
+		return false
+	}
+	if isValue(v) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return false
+		}
+		return hasValue(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && hasValue(v.Field(i), depth+1) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasValue(v.Index(i), depth+1) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasValue(iter.Value(), depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
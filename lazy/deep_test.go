@@ -0,0 +1,63 @@
+package lazy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestEvalDeep(t *testing.T) {
+	a := assert.New(t)
+	type Item struct {
+		Name  string
+		Count Value
+		Any   interface{}
+	}
+	items := []Item{
+		{Name: "a", Count: New(1), Any: New("any")},
+		{Name: "b", Count: Map(New(1), func(v interface{}) (interface{}, error) { return v.(int) + 1, nil })},
+	}
+	data := map[string]interface{}{
+		"items":  items,
+		"ptr":    &Item{Name: "c", Any: New([]Value{New(3)})},
+		"nested": New(New("nested")),
+		"plain":  "plain",
+	}
+	v, err := EvalDeep(context.Background(), data)
+	a.Nil(err)
+	buff, err := json.Marshal(v)
+	a.Nil(err)
+	a.EqStr(
+		`{"items":[{"Name":"a","Count":1,"Any":"any"},{"Name":"b","Count":2,"Any":null}],"nested":"nested","plain":"plain","ptr":{"Name":"c","Count":null,"Any":[3]}}`,
+		string(buff),
+	)
+	// the original data is not modified.
+	_, ok := items[0].Any.(Value)
+	a.OK(ok)
+	_, ok = data["nested"].(Value)
+	a.OK(ok)
+}
+
+func TestEvalDeep_NoValue(t *testing.T) {
+	a := assert.New(t)
+	data := []string{"a", "b"}
+	v, err := EvalDeep(context.Background(), data)
+	a.Nil(err)
+	a.OK(&data[0] == &(v.([]string))[0])
+
+	v, err = EvalDeep(context.Background(), nil)
+	a.Nil(err)
+	a.Nil(v)
+}
+
+func TestEvalDeep_Error(t *testing.T) {
+	a := assert.New(t)
+	_, err := EvalDeep(context.Background(), map[string]interface{}{
+		"ok": New(1),
+		"ng": []interface{}{failure("error")},
+	})
+	a.NotNil(err)
+	a.EqStr("error", err.Error())
+}
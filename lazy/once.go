@@ -0,0 +1,71 @@
+package lazy
+
+import (
+	"context"
+	"sync"
+)
+
+// Once returns a Value that evaluates `v` only once and returns the same result after that.
+// It is safe for concurrent use and concurrent Evals wait for the first evaluation until their contexts are done.
+// Use RetryOnError not to cache the error. Context errors such as context.Canceled are never cached.
+func Once(v Value, options ...Option) Value {
+	return &once{
+		v:      v,
+		config: newConfig(options),
+	}
+}
+
+type once struct {
+	v      Value
+	config *config
+
+	mu        sync.Mutex
+	evaluated bool
+	value     interface{}
+	err       error
+	inflight  chan struct{} // closed when the evaluation in flight finishes
+}
+
+// Eval implements Value#Eval
+func (o *once) Eval(ctx context.Context) (interface{}, error) {
+	for {
+		o.mu.Lock()
+		if o.evaluated {
+			o.mu.Unlock()
+			return o.value, o.err
+		}
+		inflight := o.inflight
+		if inflight == nil {
+			break
+		}
+		o.mu.Unlock()
+		select {
+		case <-inflight:
+			// evaluated, or evaluate again if the result is not cached.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	inflight := make(chan struct{})
+	o.inflight = inflight
+	o.mu.Unlock()
+
+	var value interface{}
+	var err error
+	completed := false
+	defer func() {
+		// when `v` panics, nothing is cached so that the next Eval evaluates it again.
+		o.mu.Lock()
+		o.inflight = nil
+		if completed && o.config.shouldCache(err) {
+			o.evaluated = true
+			o.value = value
+			o.err = err
+		}
+		o.mu.Unlock()
+		close(inflight)
+	}()
+	value, err = o.v.Eval(ctx)
+	completed = true
+	return value, err
+}
@@ -0,0 +1,123 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestOnce(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	v := Once(Func(func(context.Context) (interface{}, error) {
+		return int(atomic.AddInt32(&i, 1)), nil
+	}))
+	var wg sync.WaitGroup
+	for j := 0; j < 10; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := v.Eval(context.Background())
+			a.Nil(err)
+			a.EqInt(1, got.(int))
+		}()
+	}
+	wg.Wait()
+	a.EqInt(1, int(atomic.LoadInt32(&i)))
+}
+
+func TestOnce_Error(t *testing.T) {
+	a := assert.New(t)
+	var i int
+	f := Func(func(context.Context) (interface{}, error) {
+		i++
+		if i == 1 {
+			return nil, fmt.Errorf("error")
+		}
+		return i, nil
+	})
+
+	v := Once(f)
+	_, err := v.Eval(context.Background())
+	a.NotNil(err)
+	_, err = v.Eval(context.Background())
+	a.NotNil(err)
+	a.EqInt(1, i)
+
+	i = 0
+	v = Once(f, RetryOnError())
+	_, err = v.Eval(context.Background())
+	a.NotNil(err)
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(2, got.(int))
+	got, err = v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(2, got.(int))
+}
+
+func TestOnce_ContextError(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	v := Once(Func(func(ctx context.Context) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return int(atomic.AddInt32(&i, 1)), nil
+	}))
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := v.Eval(canceled)
+	a.OK(err == context.Canceled)
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(1, got.(int))
+}
+
+func TestOnce_WaitWithContext(t *testing.T) {
+	a := assert.New(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	v := Once(Func(func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return 1, nil
+	}))
+	go v.Eval(context.Background())
+	<-started
+
+	// the waiter returns by its own context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := v.Eval(ctx)
+	a.OK(err == context.Canceled)
+
+	close(release)
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(1, got.(int))
+}
+
+func TestOnce_Panic(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	v := Once(Func(func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&i, 1) == 1 {
+			panic("boom")
+		}
+		return int(i), nil
+	}))
+	func() {
+		defer func() {
+			a.OK(recover() != nil)
+		}()
+		v.Eval(context.Background())
+	}()
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(2, got.(int))
+}
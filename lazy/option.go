@@ -0,0 +1,83 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yssk22/go/x/xtime"
+)
+
+// Option is a function to configure Once and WithTTL
+type Option func(*config) *config
+
+type config struct {
+	retryOnError      bool
+	staleWhileRefresh bool
+	clock             xtime.Clock
+}
+
+func newConfig(options []Option) *config {
+	c := &config{
+		clock: xtime.SystemClock,
+	}
+	for _, f := range options {
+		c = f(c)
+	}
+	return c
+}
+
+// shouldCache returns true if the result with `err` should be cached.
+// Context errors are never cached since they are specific to the caller.
+func (c *config) shouldCache(err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return !c.retryOnError
+}
+
+// RetryOnError returns an Option not to cache the error so that the next Eval evaluates the value again.
+// By default, the error is cached as well as the value, except context errors.
+func RetryOnError() Option {
+	return func(c *config) *config {
+		c.retryOnError = true
+		return c
+	}
+}
+
+// StaleWhileRefresh returns an Option for WithTTL to return the expired value while refreshing it in background.
+func StaleWhileRefresh() Option {
+	return func(c *config) *config {
+		c.staleWhileRefresh = true
+		return c
+	}
+}
+
+// Clock returns an Option to set the clock, mainly for tests.
+func Clock(clock xtime.Clock) Option {
+	return func(c *config) *config {
+		c.clock = clock
+		return c
+	}
+}
+
+// detached is a context that keeps values of the parent but is never canceled,
+// used for background refreshes that outlive the caller.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
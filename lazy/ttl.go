@@ -0,0 +1,111 @@
+package lazy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/x/xlog"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "lazy"
+
+// WithTTL returns a Value that caches the result of `v` for `ttl` and evaluates it again after it expires.
+// Concurrent Evals wait for the evaluation in flight until their contexts are done.
+// With StaleWhileRefresh, the expired value is returned immediately while it is refreshed in background.
+func WithTTL(v Value, ttl time.Duration, options ...Option) Value {
+	return &ttlValue{
+		v:      v,
+		ttl:    ttl,
+		config: newConfig(options),
+	}
+}
+
+type ttlValue struct {
+	v      Value
+	ttl    time.Duration
+	config *config
+
+	mu         sync.Mutex
+	evaluated  bool
+	value      interface{}
+	err        error
+	expiry     time.Time
+	refreshing bool
+	inflight   chan struct{} // closed when the evaluation in flight finishes
+}
+
+// Eval implements Value#Eval
+func (t *ttlValue) Eval(ctx context.Context) (interface{}, error) {
+	for {
+		t.mu.Lock()
+		if t.evaluated {
+			if t.config.clock.Now().Before(t.expiry) {
+				t.mu.Unlock()
+				return t.value, t.err
+			}
+			if t.config.staleWhileRefresh && t.err == nil {
+				if !t.refreshing {
+					t.refreshing = true
+					go t.refresh(detached{ctx})
+				}
+				t.mu.Unlock()
+				return t.value, t.err
+			}
+		}
+		inflight := t.inflight
+		if inflight == nil {
+			break
+		}
+		t.mu.Unlock()
+		select {
+		case <-inflight:
+			// evaluated, or evaluate again if the result is not cached.
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	inflight := make(chan struct{})
+	t.inflight = inflight
+	t.mu.Unlock()
+
+	var value interface{}
+	var err error
+	completed := false
+	defer func() {
+		t.mu.Lock()
+		t.inflight = nil
+		if completed {
+			t.store(value, err)
+		}
+		t.mu.Unlock()
+		close(inflight)
+	}()
+	value, err = t.v.Eval(ctx)
+	completed = true
+	return value, err
+}
+
+func (t *ttlValue) refresh(ctx context.Context) {
+	value, err := t.v.Eval(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refreshing = false
+	if err != nil {
+		_, logger := xlog.WithContextAndKey(ctx, "lazy.ttl", LoggerKey)
+		logger.Warnf("could not refresh the value, the stale value is used until the next refresh: %v", err)
+		return
+	}
+	t.store(value, err)
+}
+
+func (t *ttlValue) store(value interface{}, err error) {
+	if !t.config.shouldCache(err) {
+		return
+	}
+	t.evaluated = true
+	t.value = value
+	t.err = err
+	t.expiry = t.config.clock.Now().Add(t.ttl)
+}
@@ -0,0 +1,135 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestWithTTL(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var i int
+	v := WithTTL(Func(func(context.Context) (interface{}, error) {
+		i++
+		return i, nil
+	}), time.Minute, Clock(clock))
+	got, _ := v.Eval(context.Background())
+	a.EqInt(1, got.(int))
+	clock.Advance(30 * time.Second)
+	got, _ = v.Eval(context.Background())
+	a.EqInt(1, got.(int))
+	clock.Advance(30 * time.Second)
+	got, _ = v.Eval(context.Background())
+	a.EqInt(2, got.(int))
+}
+
+func TestWithTTL_StaleWhileRefresh(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var i int32
+	refreshed := make(chan struct{}, 1)
+	v := WithTTL(Func(func(ctx context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&i, 1)
+		if n > 1 {
+			a.Nil(ctx.Err())
+			refreshed <- struct{}{}
+		}
+		return int(n), nil
+	}), time.Minute, Clock(clock), StaleWhileRefresh())
+	got, _ := v.Eval(context.Background())
+	a.EqInt(1, got.(int))
+	clock.Advance(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got, _ = v.Eval(ctx)
+	cancel()
+	a.EqInt(1, got.(int))
+	<-refreshed
+	for {
+		got, _ = v.Eval(context.Background())
+		if got.(int) == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	a.EqInt(2, int(atomic.LoadInt32(&i)))
+}
+
+func TestWithTTL_Error(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	var i int
+	v := WithTTL(Func(func(context.Context) (interface{}, error) {
+		i++
+		return nil, fmt.Errorf("error")
+	}), time.Minute, Clock(clock))
+	v.Eval(context.Background())
+	v.Eval(context.Background())
+	a.EqInt(1, i)
+	clock.Advance(time.Minute)
+	v.Eval(context.Background())
+	a.EqInt(2, i)
+
+	i = 0
+	v = WithTTL(Func(func(context.Context) (interface{}, error) {
+		i++
+		return nil, fmt.Errorf("error")
+	}), time.Minute, Clock(clock), RetryOnError())
+	v.Eval(context.Background())
+	v.Eval(context.Background())
+	a.EqInt(2, i)
+}
+
+func TestWithTTL_ContextError(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	v := WithTTL(Func(func(ctx context.Context) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return 1, nil
+	}), time.Minute, Clock(clock))
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	_, err := v.Eval(ctx)
+	a.OK(err == context.DeadlineExceeded)
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(1, got.(int))
+}
+
+func TestWithTTL_WaitWithContext(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var i int32
+	v := WithTTL(Func(func(ctx context.Context) (interface{}, error) {
+		n := atomic.AddInt32(&i, 1)
+		if n == 1 {
+			close(started)
+			<-release
+		}
+		return int(n), nil
+	}), time.Minute, Clock(clock))
+	go v.Eval(context.Background())
+	<-started
+
+	// the waiter returns by its own context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := v.Eval(ctx)
+	a.OK(err == context.Canceled)
+
+	close(release)
+	got, err := v.Eval(context.Background())
+	a.Nil(err)
+	a.EqInt(1, got.(int))
+	a.EqInt(1, int(atomic.LoadInt32(&i)))
+}
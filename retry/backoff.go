@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Jitter is a enum for the jitter strategy of ExponentialBackoff
+type Jitter int
+
+// Available Jitter values
+const (
+	// NoJitter uses min * 2^(attempt-1) as it is.
+	NoJitter Jitter = iota
+	// FullJitter uses a random duration between min and min * 2^(attempt-1).
+	FullJitter
+	// DecorrelatedJitter uses a random duration between min and 3 times of the previous backoff.
+	DecorrelatedJitter
+)
+
+// ExponentialBackoff returns a Backoff that increases the backoff exponentially from `min` up to `max` with the jitter.
+func ExponentialBackoff(min, max time.Duration, jitter Jitter) Backoff {
+	return &exponentialBackoff{
+		min:    min,
+		max:    max,
+		jitter: jitter,
+	}
+}
+
+type exponentialBackoff struct {
+	min    time.Duration
+	max    time.Duration
+	jitter Jitter
+}
+
+// Calc implements Backoff#Calc
+func (b *exponentialBackoff) Calc(ctx context.Context, attempt int) time.Duration {
+	return b.calc(attempt, getState(ctx))
+}
+
+func (b *exponentialBackoff) calc(attempt int, s *state) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	switch b.jitter {
+	case FullJitter:
+		return b.between(b.min, b.exp(attempt))
+	case DecorrelatedJitter:
+		prev := b.min
+		if s != nil && s.lastDelay > 0 {
+			prev = s.lastDelay
+		}
+		return b.between(b.min, b.cap(float64(prev)*3))
+	default:
+		return b.exp(attempt)
+	}
+}
+
+func (b *exponentialBackoff) exp(attempt int) time.Duration {
+	return b.cap(float64(b.min) * math.Pow(2, float64(attempt-1)))
+}
+
+func (b *exponentialBackoff) cap(d float64) time.Duration {
+	if b.max > 0 && d > float64(b.max) {
+		return b.max
+	}
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+func (b *exponentialBackoff) between(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(randFloat64()*float64(max-min))
+}
+
+// Budget returns a Checker to check the total elapsed time since Do starts is less than `d`.
+func Budget(d time.Duration) Checker {
+	return &budget{
+		d: d,
+	}
+}
+
+type budget struct {
+	d time.Duration
+}
+
+func (b *budget) NeedRetry(ctx context.Context, attempt int, err error) bool {
+	return withinBudget(getState(ctx), b.d)
+}
+
+func withinBudget(s *state, d time.Duration) bool {
+	if s == nil {
+		return true
+	}
+	return clock.Now().Sub(s.start) < d
+}
+
+// random is seeded by the current time so that the jitters are not synchronized across processes.
+var random = struct {
+	sync.Mutex
+	r *rand.Rand
+}{
+	r: rand.New(rand.NewSource(time.Now().UnixNano())),
+}
+
+// randFloat64 can be replaced in tests for deterministic jitters.
+var randFloat64 = func() float64 {
+	random.Lock()
+	defer random.Unlock()
+	return random.r.Float64()
+}
@@ -0,0 +1,162 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xnet/xhttp/xhttptest"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func useFakeClock(f func(*xtime.FakeClock)) {
+	fake := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	fake.AutoAdvance = true
+	clock = fake
+	defer func() {
+		clock = xtime.SystemClock
+	}()
+	f(fake)
+}
+
+func useRand(v float64, f func()) {
+	orig := randFloat64
+	randFloat64 = func() float64 { return v }
+	defer func() {
+		randFloat64 = orig
+	}()
+	f()
+}
+
+func alwaysFail(_ context.Context) error {
+	return fmt.Errorf("Need retry")
+}
+
+func Test_ExponentialBackoff(t *testing.T) {
+	t.Run("NoJitter", func(t *testing.T) {
+		a := assert.New(t)
+		useFakeClock(func(fake *xtime.FakeClock) {
+			a.NotNil(Do(context.Background(), alwaysFail, ExponentialBackoff(100*time.Millisecond, 500*time.Millisecond, NoJitter), MaxRetries(5)))
+			slept := fake.Slept()
+			a.EqInt(4, len(slept))
+			a.EqInt64(int64(100*time.Millisecond), int64(slept[0]))
+			a.EqInt64(int64(200*time.Millisecond), int64(slept[1]))
+			a.EqInt64(int64(400*time.Millisecond), int64(slept[2]))
+			a.EqInt64(int64(500*time.Millisecond), int64(slept[3]))
+		})
+	})
+
+	t.Run("FullJitter", func(t *testing.T) {
+		a := assert.New(t)
+		useRand(0.5, func() {
+			useFakeClock(func(fake *xtime.FakeClock) {
+				a.NotNil(Do(context.Background(), alwaysFail, ExponentialBackoff(100*time.Millisecond, time.Second, FullJitter), MaxRetries(4)))
+				slept := fake.Slept()
+				a.EqInt(3, len(slept))
+				a.EqInt64(int64(100*time.Millisecond), int64(slept[0]))
+				a.EqInt64(int64(150*time.Millisecond), int64(slept[1]))
+				a.EqInt64(int64(250*time.Millisecond), int64(slept[2]))
+			})
+		})
+	})
+
+	t.Run("DecorrelatedJitter", func(t *testing.T) {
+		a := assert.New(t)
+		useRand(0.5, func() {
+			useFakeClock(func(fake *xtime.FakeClock) {
+				a.NotNil(Do(context.Background(), alwaysFail, ExponentialBackoff(100*time.Millisecond, 500*time.Millisecond, DecorrelatedJitter), MaxRetries(4)))
+				slept := fake.Slept()
+				a.EqInt(3, len(slept))
+				a.EqInt64(int64(200*time.Millisecond), int64(slept[0]))
+				a.EqInt64(int64(300*time.Millisecond), int64(slept[1]))
+				a.EqInt64(int64(300*time.Millisecond), int64(slept[2]))
+			})
+		})
+	})
+}
+
+func Test_Budget(t *testing.T) {
+	a := assert.New(t)
+	useFakeClock(func(fake *xtime.FakeClock) {
+		var i int
+		err := Do(context.Background(), func(ctx context.Context) error {
+			i++
+			return fmt.Errorf("Need retry")
+		}, ConstBackoff(time.Second), Budget(3500*time.Millisecond))
+		a.NotNil(err)
+		a.EqInt(5, i)
+	})
+}
+
+func Test_HTTPTransport_RetryAfter(t *testing.T) {
+	a := assert.New(t)
+	useFakeClock(func(fake *xtime.FakeClock) {
+		var i = 0
+		xhttptest.UseStubServer(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i++
+				if i < 3 {
+					w.Header().Set("Retry-After", "3")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(200)
+			}),
+			func(s *xhttptest.StubServer) {
+				client := s.Client(
+					map[string]string{
+						"http://example.com/": "/",
+					},
+					&http.Client{
+						Transport: NewHTTPTransport(
+							http.DefaultTransport,
+							HTTPAnd(HTTPRetryUntil(5), HTTPRetryOnServerErrorOrTooManyRequests()),
+							HTTPExponentialBackoff(100*time.Millisecond, 10*time.Second, NoJitter),
+						),
+					},
+				)
+				resp, err := client.Get("http://example.com/")
+				a.Nil(err)
+				a.EqInt(200, resp.StatusCode)
+			},
+		)
+		slept := fake.Slept()
+		a.EqInt(2, len(slept))
+		a.EqInt64(int64(3*time.Second), int64(slept[0]))
+		a.EqInt64(int64(3*time.Second), int64(slept[1]))
+	})
+}
+
+func Test_HTTPRetryWithin(t *testing.T) {
+	a := assert.New(t)
+	useFakeClock(func(fake *xtime.FakeClock) {
+		var i = 0
+		xhttptest.UseStubServer(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i++
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}),
+			func(s *xhttptest.StubServer) {
+				client := s.Client(
+					map[string]string{
+						"http://example.com/": "/",
+					},
+					&http.Client{
+						Transport: NewHTTPTransport(
+							http.DefaultTransport,
+							HTTPAnd(HTTPRetryWithin(2500*time.Millisecond), HTTPRetryOnServerError()),
+							HTTPConstBackoff(time.Second),
+						),
+					},
+				)
+				resp, err := client.Get("http://example.com/")
+				a.Nil(err)
+				a.EqInt(503, resp.StatusCode)
+			},
+		)
+		a.EqInt(4, i)
+	})
+}
@@ -0,0 +1,366 @@
+// Package breaker provides the circuit breaker to stop calling a downstream service that is hard-down.
+//
+// A Breaker starts in StateClosed and trips to StateOpen when the failures exceed the threshold.
+// While open, calls fail immediately with ErrOpen until the cooldown passes, then the breaker
+// moves to StateHalfOpen and allows a limited number of trial calls to decide to close or open again.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/retry"
+	"github.com/yssk22/go/x/xlog"
+	"github.com/yssk22/go/x/xtime"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "retry.breaker"
+
+// State is a enum for the breaker state
+type State int
+
+// Available State values
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// MarshalJSON implements json.Marshaler
+func (s State) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", s.String())), nil
+}
+
+// ErrOpen is an error returned when the breaker does not allow calls.
+var ErrOpen = errors.New("breaker: circuit is open")
+
+// Option is a function to configure *Breaker
+type Option func(*config) *config
+
+type config struct {
+	consecutiveFailures int
+	failureRate         float64
+	minRequests         int
+	window              time.Duration
+	cooldown            time.Duration
+	halfOpenRequests    int
+	clock               xtime.Clock
+	onStateChange       func(name string, from, to State)
+	idleTimeout         time.Duration
+}
+
+// DefaultIdleTimeout is the default duration to keep an unused closed breaker in a Group.
+const DefaultIdleTimeout = 10 * time.Minute
+
+func newConfig(options []Option) *config {
+	c := &config{
+		consecutiveFailures: 5,
+		cooldown:            30 * time.Second,
+		halfOpenRequests:    1,
+		clock:               xtime.SystemClock,
+		idleTimeout:         DefaultIdleTimeout,
+	}
+	for _, f := range options {
+		c = f(c)
+	}
+	return c
+}
+
+// ConsecutiveFailures returns an Option to trip the breaker when `n` calls fail in a row.
+func ConsecutiveFailures(n int) Option {
+	return func(c *config) *config {
+		c.consecutiveFailures = n
+		return c
+	}
+}
+
+// FailureRate returns an Option to trip the breaker when the failure rate in `window` reaches `rate`.
+// The rate is not evaluated until the number of calls in the window reaches `minRequests`.
+func FailureRate(rate float64, minRequests int, window time.Duration) Option {
+	return func(c *config) *config {
+		c.failureRate = rate
+		c.minRequests = minRequests
+		c.window = window
+		return c
+	}
+}
+
+// Cooldown returns an Option to set the duration to keep the breaker open before trial calls.
+func Cooldown(d time.Duration) Option {
+	return func(c *config) *config {
+		c.cooldown = d
+		return c
+	}
+}
+
+// HalfOpenRequests returns an Option to set the number of trial calls in StateHalfOpen.
+// The breaker is closed when all of them succeed.
+func HalfOpenRequests(n int) Option {
+	return func(c *config) *config {
+		c.halfOpenRequests = n
+		return c
+	}
+}
+
+// Clock returns an Option to set the clock, mainly for tests.
+func Clock(clock xtime.Clock) Option {
+	return func(c *config) *config {
+		c.clock = clock
+		return c
+	}
+}
+
+// IdleTimeout returns an Option for Group to evict the closed breakers not used for `d`.
+// 0 never evicts the breakers. The default is DefaultIdleTimeout.
+func IdleTimeout(d time.Duration) Option {
+	return func(c *config) *config {
+		c.idleTimeout = d
+		return c
+	}
+}
+
+// OnStateChange returns an Option to set the callback on state transitions.
+func OnStateChange(f func(name string, from, to State)) Option {
+	return func(c *config) *config {
+		c.onStateChange = f
+		return c
+	}
+}
+
+// Breaker is a circuit breaker shared by callers to the same downstream.
+type Breaker struct {
+	name   string
+	config *config
+
+	mu                  sync.Mutex
+	state               State
+	openedAt            time.Time
+	consecutiveFailures int
+	windowStart         time.Time
+	windowRequests      int
+	windowFailures      int
+	halfOpenInFlight    int
+	halfOpenSuccesses   int
+	generation          uint64 // incremented on each transition to ignore the reports of calls in previous states
+	inFlight            int    // the calls allowed but not reported yet
+	transitions         []transition
+}
+
+type transition struct {
+	from State
+	to   State
+}
+
+// New returns a new *Breaker. The default configuration trips after 5 consecutive failures and cools down for 30 seconds.
+func New(name string, options ...Option) *Breaker {
+	return newBreaker(name, newConfig(options))
+}
+
+func newBreaker(name string, c *config) *Breaker {
+	return &Breaker{
+		name:        name,
+		config:      c,
+		windowStart: c.clock.Now(),
+	}
+}
+
+// Name returns the name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.lock()
+	defer b.unlock()
+	b.checkCooldown()
+	return b.state
+}
+
+// isIdle returns true if the breaker is closed and has no calls in flight without notifying transitions,
+// so that it can be called while the group lock is held.
+func (b *Breaker) isIdle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == StateClosed && b.inFlight == 0
+}
+
+// Allow checks if a call is allowed. If allowed, the caller must report the result by the returned func.
+func (b *Breaker) Allow() (func(success bool), error) {
+	b.lock()
+	defer b.unlock()
+	b.checkCooldown()
+	switch b.state {
+	case StateOpen:
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenInFlight+b.halfOpenSuccesses >= b.config.halfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.halfOpenInFlight++
+	}
+	b.inFlight++
+	var once sync.Once
+	generation := b.generation
+	return func(success bool) {
+		once.Do(func() {
+			b.report(generation, success)
+		})
+	}, nil
+}
+
+// Do runs `task` if the breaker allows. The call fails when task returns an error.
+func (b *Breaker) Do(ctx context.Context, task func(context.Context) error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	// done is deferred so that a panicking task is reported as a failure and does not leak a half-open slot.
+	var success bool
+	defer func() {
+		done(success)
+	}()
+	err = task(ctx)
+	success = err == nil
+	return err
+}
+
+// Wrap returns a task for retry.Do that runs through the breaker.
+// Use it with StopOnOpen not to wait backoffs while the breaker is open.
+func (b *Breaker) Wrap(task func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return b.Do(ctx, task)
+	}
+}
+
+// StopOnOpen returns a retry.Checker that stops retries when the error is ErrOpen, otherwise delegates to `c`.
+func StopOnOpen(c retry.Checker) retry.Checker {
+	return &stopOnOpen{
+		c: c,
+	}
+}
+
+type stopOnOpen struct {
+	c retry.Checker
+}
+
+func (s *stopOnOpen) NeedRetry(ctx context.Context, attempt int, err error) bool {
+	if errors.Is(err, ErrOpen) {
+		return false
+	}
+	return s.c.NeedRetry(ctx, attempt, err)
+}
+
+func (b *Breaker) report(generation uint64, success bool) {
+	b.lock()
+	defer b.unlock()
+	b.inFlight--
+	if generation != b.generation {
+		// the call started before the last transition.
+		return
+	}
+	now := b.config.clock.Now()
+	switch b.state {
+	case StateHalfOpen:
+		b.halfOpenInFlight--
+		if !success {
+			b.transition(StateOpen, now)
+			return
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.config.halfOpenRequests {
+			b.transition(StateClosed, now)
+		}
+		return
+	}
+	if b.config.window > 0 && now.Sub(b.windowStart) >= b.config.window {
+		b.resetWindow(now)
+	}
+	b.windowRequests++
+	if success {
+		b.consecutiveFailures = 0
+		return
+	}
+	b.windowFailures++
+	b.consecutiveFailures++
+	if b.config.consecutiveFailures > 0 && b.consecutiveFailures >= b.config.consecutiveFailures {
+		b.transition(StateOpen, now)
+		return
+	}
+	if b.config.failureRate > 0 && b.windowRequests >= b.config.minRequests {
+		if float64(b.windowFailures)/float64(b.windowRequests) >= b.config.failureRate {
+			b.transition(StateOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) checkCooldown() {
+	if b.state != StateOpen {
+		return
+	}
+	now := b.config.clock.Now()
+	if now.Sub(b.openedAt) >= b.config.cooldown {
+		b.transition(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.windowRequests = 0
+	b.windowFailures = 0
+}
+
+func (b *Breaker) transition(to State, now time.Time) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.generation++
+	b.consecutiveFailures = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	b.resetWindow(now)
+	if to == StateOpen {
+		b.openedAt = now
+	}
+	b.transitions = append(b.transitions, transition{from, to})
+}
+
+func (b *Breaker) lock() {
+	b.mu.Lock()
+}
+
+// unlock releases the lock and then notifies the transitions so that callbacks can access the breaker.
+func (b *Breaker) unlock() {
+	transitions := b.transitions
+	b.transitions = nil
+	b.mu.Unlock()
+	logger := xlog.WithKey(LoggerKey)
+	for _, t := range transitions {
+		if t.to == StateOpen {
+			logger.Warnf("circuit %q: %s -> %s", b.name, t.from, t.to)
+		} else {
+			logger.Infof("circuit %q: %s -> %s", b.name, t.from, t.to)
+		}
+		if b.config.onStateChange != nil {
+			b.config.onStateChange(b.name, t.from, t.to)
+		}
+	}
+}
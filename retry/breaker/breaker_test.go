@@ -0,0 +1,156 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/retry"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+var errTask = fmt.Errorf("task error")
+
+func fail(context.Context) error {
+	return errTask
+}
+
+func succeed(context.Context) error {
+	return nil
+}
+
+func TestBreaker_ConsecutiveFailures(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	var transitions []string
+	b := New("test", ConsecutiveFailures(3), Cooldown(10*time.Second), Clock(clock), OnStateChange(func(name string, from, to State) {
+		transitions = append(transitions, fmt.Sprintf("%s->%s", from, to))
+	}))
+	a.OK(errTask == b.Do(ctx, fail))
+	a.OK(errTask == b.Do(ctx, fail))
+	a.Nil(b.Do(ctx, succeed))
+	a.OK(errTask == b.Do(ctx, fail))
+	a.OK(errTask == b.Do(ctx, fail))
+	a.OK(StateClosed == b.State())
+	a.OK(errTask == b.Do(ctx, fail))
+	a.OK(StateOpen == b.State())
+	a.OK(ErrOpen == b.Do(ctx, succeed))
+
+	clock.Advance(10 * time.Second)
+	a.OK(StateHalfOpen == b.State())
+	a.OK(errTask == b.Do(ctx, fail))
+	a.OK(StateOpen == b.State())
+
+	clock.Advance(10 * time.Second)
+	a.Nil(b.Do(ctx, succeed))
+	a.OK(StateClosed == b.State())
+
+	a.EqInt(5, len(transitions))
+	a.EqStr("closed->open", transitions[0])
+	a.EqStr("open->half-open", transitions[1])
+	a.EqStr("half-open->open", transitions[2])
+	a.EqStr("open->half-open", transitions[3])
+	a.EqStr("half-open->closed", transitions[4])
+}
+
+func TestBreaker_FailureRate(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New("test", ConsecutiveFailures(0), FailureRate(0.5, 4, time.Minute), Clock(clock))
+	b.Do(ctx, succeed)
+	b.Do(ctx, fail)
+	b.Do(ctx, succeed)
+	a.OK(StateClosed == b.State())
+	b.Do(ctx, fail)
+	a.OK(StateOpen == b.State())
+
+	b = New("test", ConsecutiveFailures(0), FailureRate(0.5, 4, time.Minute), Clock(clock))
+	b.Do(ctx, fail)
+	b.Do(ctx, fail)
+	clock.Advance(time.Minute)
+	b.Do(ctx, succeed)
+	b.Do(ctx, succeed)
+	b.Do(ctx, fail)
+	a.OK(StateClosed == b.State())
+}
+
+func TestBreaker_HalfOpenRequests(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New("test", ConsecutiveFailures(1), HalfOpenRequests(2), Cooldown(time.Second), Clock(clock))
+	b.Do(context.Background(), fail)
+	clock.Advance(time.Second)
+	done1, err := b.Allow()
+	a.Nil(err)
+	done2, err := b.Allow()
+	a.Nil(err)
+	_, err = b.Allow()
+	a.OK(ErrOpen == err)
+	done1(true)
+	a.OK(StateHalfOpen == b.State())
+	done2(true)
+	a.OK(StateClosed == b.State())
+}
+
+func TestBreaker_RetryDo(t *testing.T) {
+	a := assert.New(t)
+	b := New("test", ConsecutiveFailures(2))
+	var i int
+	err := retry.Do(context.Background(), b.Wrap(func(ctx context.Context) error {
+		i++
+		return errTask
+	}), retry.ConstBackoff(time.Millisecond), StopOnOpen(retry.MaxRetries(10)))
+	a.OK(ErrOpen == err)
+	a.EqInt(2, i)
+}
+
+func TestBreaker_staleReport(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New("test", ConsecutiveFailures(1), Cooldown(10*time.Second), HalfOpenRequests(1), Clock(clock))
+	// a slow call admitted while closed
+	slow, err := b.Allow()
+	a.Nil(err)
+	done, err := b.Allow()
+	a.Nil(err)
+	done(false)
+	a.OK(StateOpen == b.State())
+	clock.Advance(10 * time.Second)
+	a.OK(StateHalfOpen == b.State())
+	probe, err := b.Allow()
+	a.Nil(err)
+
+	// the slow call neither closes the breaker nor frees the half-open slot
+	slow(true)
+	a.OK(StateHalfOpen == b.State())
+	_, err = b.Allow()
+	a.OK(ErrOpen == err)
+	probe(true)
+	a.OK(StateClosed == b.State())
+}
+
+func TestBreaker_panic(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New("test", ConsecutiveFailures(1), Cooldown(10*time.Second), Clock(clock))
+	a.OK(errTask == b.Do(ctx, fail))
+	clock.Advance(10 * time.Second)
+	func() {
+		defer func() {
+			a.NotNil(recover())
+		}()
+		b.Do(ctx, func(context.Context) error {
+			panic("boom")
+		})
+	}()
+	// the panic is reported as a failure
+	a.OK(StateOpen == b.State())
+	clock.Advance(10 * time.Second)
+	a.Nil(b.Do(ctx, succeed))
+	a.OK(StateClosed == b.State())
+}
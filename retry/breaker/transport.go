@@ -0,0 +1,140 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Group is a set of breakers keyed by names, created on demand with the same options.
+// The closed breakers not used for IdleTimeout are evicted so that the group does not grow unbounded.
+type Group struct {
+	config    *config
+	mu        sync.Mutex
+	breakers  map[string]*groupEntry
+	lastSweep time.Time
+}
+
+type groupEntry struct {
+	breaker  *Breaker
+	lastUsed time.Time
+}
+
+// NewGroup returns a new *Group
+func NewGroup(options ...Option) *Group {
+	c := newConfig(options)
+	return &Group{
+		config:    c,
+		breakers:  make(map[string]*groupEntry),
+		lastSweep: c.clock.Now(),
+	}
+}
+
+// Get returns a *Breaker for `name`
+func (g *Group) Get(name string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.config.clock.Now()
+	g.sweep(now)
+	e, ok := g.breakers[name]
+	if !ok {
+		e = &groupEntry{breaker: newBreaker(name, g.config)}
+		g.breakers[name] = e
+	}
+	e.lastUsed = now
+	return e.breaker
+}
+
+// sweep evicts the idle closed breakers at most once per the idle timeout. g.mu must be held.
+// Open and half-open breakers and the breakers with calls in flight are kept not to lose the state of the downstream.
+func (g *Group) sweep(now time.Time) {
+	if g.config.idleTimeout <= 0 || now.Sub(g.lastSweep) < g.config.idleTimeout {
+		return
+	}
+	g.lastSweep = now
+	for name, e := range g.breakers {
+		if now.Sub(e.lastUsed) >= g.config.idleTimeout && e.breaker.isIdle() {
+			delete(g.breakers, name)
+		}
+	}
+}
+
+// States returns the current states of the breakers in the group for health endpoints.
+func (g *Group) States() map[string]State {
+	g.mu.Lock()
+	breakers := make(map[string]*Breaker, len(g.breakers))
+	for name, e := range g.breakers {
+		breakers[name] = e.breaker
+	}
+	g.mu.Unlock()
+	states := make(map[string]State)
+	for name, b := range breakers {
+		states[name] = b.State()
+	}
+	return states
+}
+
+// TransportOption is a function to configure the transport
+type TransportOption func(*transport) *transport
+
+// IsFailure returns a TransportOption to classify the round trip result as a failure.
+// The default is an error or http status code >= 500.
+func IsFailure(f func(*http.Response, error) bool) TransportOption {
+	return func(t *transport) *transport {
+		t.isFailure = f
+		return t
+	}
+}
+
+// KeyFunc returns a TransportOption to select the breaker for the request. The default is req.URL.Host.
+func KeyFunc(f func(*http.Request) string) TransportOption {
+	return func(t *transport) *transport {
+		t.key = f
+		return t
+	}
+}
+
+type transport struct {
+	base      http.RoundTripper
+	group     *Group
+	isFailure func(*http.Response, error) bool
+	key       func(*http.Request) string
+}
+
+// NewTransport returns a http.RoundTripper that protects `base` by the breakers in `group` (per host by default).
+// It can be a base of retry.NewHTTPTransport so that retries stop hitting the downstream while the breaker is open.
+func NewTransport(base http.RoundTripper, group *Group, options ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base:  base,
+		group: group,
+		isFailure: func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		},
+		key: func(req *http.Request) string {
+			return req.URL.Host
+		},
+	}
+	for _, f := range options {
+		t = f(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper#RoundTrip
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.group.Get(t.key(req))
+	done, err := b.Allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w (%s)", err, b.Name())
+	}
+	resp, err := t.base.RoundTrip(req)
+	done(!t.isFailure(resp, err))
+	return resp, err
+}
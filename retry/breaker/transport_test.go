@@ -0,0 +1,88 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xnet/xhttp/xhttptest"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestTransport(t *testing.T) {
+	a := assert.New(t)
+	var i = 0
+	group := NewGroup(ConsecutiveFailures(2))
+	xhttptest.UseStubServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			i++
+			w.WriteHeader(500)
+		}),
+		func(s *xhttptest.StubServer) {
+			client := s.Client(
+				map[string]string{
+					"http://example.com/": "/",
+				},
+				&http.Client{
+					Transport: NewTransport(http.DefaultTransport, group),
+				},
+			)
+			for j := 0; j < 2; j++ {
+				resp, err := client.Get("http://example.com/")
+				a.Nil(err)
+				a.EqInt(500, resp.StatusCode)
+			}
+			_, err := client.Get("http://example.com/")
+			a.NotNil(err)
+			a.OK(errors.Is(err, ErrOpen))
+		},
+	)
+	a.EqInt(2, i)
+	states := group.States()
+	a.EqInt(1, len(states))
+	for _, s := range states {
+		a.OK(StateOpen == s)
+	}
+}
+
+func TestGroup_IdleTimeout(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := NewGroup(ConsecutiveFailures(1), Cooldown(time.Hour), Clock(clock), IdleTimeout(time.Minute))
+	closed := g.Get("closed")
+	a.Nil(closed.Do(ctx, succeed))
+	open := g.Get("open")
+	a.OK(errTask == open.Do(ctx, fail))
+	a.EqInt(2, len(g.States()))
+
+	clock.Advance(time.Minute)
+	g.Get("other")
+	// the open breaker is kept
+	states := g.States()
+	a.EqInt(2, len(states))
+	a.OK(StateOpen == states["open"])
+	a.OK(open == g.Get("open"))
+	a.OK(closed != g.Get("closed"))
+}
+
+func TestGroup_IdleTimeout_inFlight(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := NewGroup(ConsecutiveFailures(2), Cooldown(time.Hour), Clock(clock), IdleTimeout(time.Minute))
+	slow := g.Get("slow")
+	done, err := slow.Allow()
+	a.Nil(err)
+
+	clock.Advance(time.Minute)
+	g.Get("other")
+	// the breaker with a call in flight is kept and counts its failure
+	done(false)
+	a.OK(slow == g.Get("slow"))
+	a.OK(errTask == slow.Do(ctx, fail))
+	a.OK(StateOpen == g.States()["slow"])
+}
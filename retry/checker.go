@@ -0,0 +1,139 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/yssk22/go/x/xerrors"
+)
+
+// And is a AND combination of multiple Checker instances.
+func And(checkers ...Checker) Checker {
+	return CheckerFunc(func(ctx context.Context, attempt int, err error) bool {
+		for _, c := range checkers {
+			if !c.NeedRetry(ctx, attempt, err) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or is a OR combination of multiple Checker instances.
+func Or(checkers ...Checker) Checker {
+	return CheckerFunc(func(ctx context.Context, attempt int, err error) bool {
+		for _, c := range checkers {
+			if c.NeedRetry(ctx, attempt, err) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not returns a Checker that negates `c`
+func Not(c Checker) Checker {
+	return CheckerFunc(func(ctx context.Context, attempt int, err error) bool {
+		return !c.NeedRetry(ctx, attempt, err)
+	})
+}
+
+// CheckerFunc is a func to implement Checker
+type CheckerFunc func(context.Context, int, error) bool
+
+// NeedRetry implements Checker#NeedRetry
+func (f CheckerFunc) NeedRetry(ctx context.Context, attempt int, err error) bool {
+	return f(ctx, attempt, err)
+}
+
+// IfError returns a Checker that needs retries when f(err) returns true.
+func IfError(f func(error) bool) Checker {
+	return CheckerFunc(func(ctx context.Context, attempt int, err error) bool {
+		return f(err)
+	})
+}
+
+// IfRetryable returns a Checker that needs retries when the error is classified as retryable by IsRetryable.
+func IfRetryable() Checker {
+	return IfError(IsRetryable)
+}
+
+// Retryable is an interface for errors that know if the operation can be retried.
+type Retryable interface {
+	Retryable() bool
+}
+
+// Temporary is an interface for errors that represent a temporary failure (as net.Error does).
+type Temporary interface {
+	Temporary() bool
+}
+
+// Classifier is a function to classify errors that does not implement Retryable.
+// `ok` should be false if the classifier does not know the error.
+type Classifier func(err error) (retryable bool, ok bool)
+
+var classifiers struct {
+	sync.RWMutex
+	list []Classifier
+}
+
+// RegisterClassifier registers a Classifier used by IsRetryable.
+// Packages that cannot implement Retryable on their errors (e.g. errors from third party libraries) can use this in init().
+func RegisterClassifier(c Classifier) {
+	classifiers.Lock()
+	defer classifiers.Unlock()
+	classifiers.list = append(classifiers.list, c)
+}
+
+// IsRetryable returns true if the error is retryable.
+//
+//   - context.Canceled and context.DeadlineExceeded are never retryable.
+//   - errors implementing Retryable or Temporary are classified by themselves.
+//   - net.Error timeouts are retryable.
+//   - errors known by the registered Classifier are classified by it.
+//   - xerrors.MultiError is retryable if all errors in it are retryable.
+//
+// Wrapped errors are unwrapped to find them.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if me, ok := err.(xerrors.MultiError); ok {
+		var found bool
+		for _, e := range me {
+			if e == nil {
+				continue
+			}
+			if !IsRetryable(e) {
+				return false
+			}
+			found = true
+		}
+		return found
+	}
+	var r Retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	var t Temporary
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	classifiers.RLock()
+	defer classifiers.RUnlock()
+	for _, c := range classifiers.list {
+		if retryable, ok := c(err); ok {
+			return retryable
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/yssk22/go/crawler/fetcher"
+	"github.com/yssk22/go/x/xerrors"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+type retryableError bool
+
+func (e retryableError) Error() string   { return "retryable error" }
+func (e retryableError) Retryable() bool { return bool(e) }
+
+func Test_AndOrNot(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	err := fmt.Errorf("error")
+	yes := CheckerFunc(func(context.Context, int, error) bool { return true })
+	no := Not(yes)
+	a.OK(And(yes, yes).NeedRetry(ctx, 1, err))
+	a.OK(!And(yes, no).NeedRetry(ctx, 1, err))
+	a.OK(Or(no, yes).NeedRetry(ctx, 1, err))
+	a.OK(!Or(no, no).NeedRetry(ctx, 1, err))
+	a.OK(And(MaxRetries(3), IfRetryable()).NeedRetry(ctx, 1, retryableError(true)))
+	a.OK(!And(MaxRetries(3), IfRetryable()).NeedRetry(ctx, 3, retryableError(true)))
+}
+
+func Test_IfError(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	errRetry := fmt.Errorf("retry")
+	c := IfError(func(err error) bool { return err == errRetry })
+	a.OK(c.NeedRetry(ctx, 1, errRetry))
+	a.OK(!c.NeedRetry(ctx, 1, fmt.Errorf("other")))
+}
+
+func Test_IsRetryable(t *testing.T) {
+	a := assert.New(t)
+	a.OK(!IsRetryable(nil))
+	a.OK(!IsRetryable(fmt.Errorf("unknown")))
+	a.OK(IsRetryable(retryableError(true)))
+	a.OK(!IsRetryable(retryableError(false)))
+	a.OK(IsRetryable(xerrors.Wrap(retryableError(true), "wrapped")))
+	a.OK(IsRetryable(fmt.Errorf("wrapped: %w", retryableError(true))))
+	a.OK(!IsRetryable(context.Canceled))
+	a.OK(!IsRetryable(context.DeadlineExceeded))
+	a.OK(IsRetryable(&net.DNSError{IsTimeout: true}))
+	a.OK(IsRetryable(&net.DNSError{IsTemporary: true}))
+	a.OK(!IsRetryable(&net.DNSError{}))
+	a.OK(IsRetryable(&fetcher.ErrHTTP{Response: &http.Response{StatusCode: 503}}))
+	a.OK(!IsRetryable(&fetcher.ErrHTTP{Response: &http.Response{StatusCode: 404}}))
+	a.OK(IsRetryable(xerrors.MultiError{nil, retryableError(true)}))
+	a.OK(!IsRetryable(xerrors.MultiError{retryableError(false), retryableError(true)}))
+	a.OK(!IsRetryable(xerrors.MultiError{nil, nil}))
+}
+
+type classifiedError struct{}
+
+func (classifiedError) Error() string { return "classified" }
+
+func Test_RegisterClassifier(t *testing.T) {
+	a := assert.New(t)
+	a.OK(!IsRetryable(classifiedError{}))
+	RegisterClassifier(func(err error) (bool, bool) {
+		_, ok := err.(classifiedError)
+		return ok, ok
+	})
+	a.OK(IsRetryable(classifiedError{}))
+	a.OK(!IsRetryable(fmt.Errorf("unknown")))
+}
@@ -0,0 +1,225 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeOption is a function to configure the hedged transport
+type HedgeOption func(*hedgedTransport) *hedgedTransport
+
+// HedgeAtPercentile returns a HedgeOption to send the hedge when the request takes longer than
+// the `p` percentile (0 < p < 100) of the last `window` latencies.
+// The delay given to NewHedgedTransport is used until `window` latencies are observed and as the lower bound.
+func HedgeAtPercentile(p float64, window int) HedgeOption {
+	return func(t *hedgedTransport) *hedgedTransport {
+		t.percentile = p
+		t.window = window
+		return t
+	}
+}
+
+// MaxConcurrentHedges returns a HedgeOption to limit the number of hedges in flight.
+// Requests are not hedged while the limit is reached.
+func MaxConcurrentHedges(n int) HedgeOption {
+	return func(t *hedgedTransport) *hedgedTransport {
+		t.hedges = make(chan struct{}, n)
+		return t
+	}
+}
+
+type hedgedTransport struct {
+	base       http.RoundTripper
+	delay      time.Duration
+	percentile float64
+	window     int
+	hedges     chan struct{}
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// NewHedgedTransport returns a http.RoundTripper that sends the second identical request when `base` does not respond
+// within `delay` and uses whichever answers first. The other request is canceled.
+// Only safe methods (GET, HEAD, OPTIONS and TRACE) are hedged and others are passed to `base` as they are.
+func NewHedgedTransport(base http.RoundTripper, delay time.Duration, options ...HedgeOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &hedgedTransport{
+		base:  base,
+		delay: delay,
+	}
+	for _, f := range options {
+		t = f(t)
+	}
+	return t
+}
+
+type hedgedResult struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// RoundTrip implements http.RoundTripper#RoundTrip
+func (t *hedgedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isSafeMethod(req.Method) {
+		return t.base.RoundTrip(req)
+	}
+	// request body should be buffered on memory since it is sent twice.
+	reqBody, err := bufferBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not allocate request body for hedged transport: %v", err)
+	}
+	start := clock.Now()
+	results := make(chan *hedgedResult, 2)
+	var cancels []context.CancelFunc
+	send := func(hedge bool) {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		r := req.WithContext(ctx)
+		if reqBody != nil {
+			r.Body = ioutil.NopCloser(io.NewSectionReader(reqBody, 0, reqBody.Size()))
+		}
+		go func() {
+			res, err := t.base.RoundTrip(r)
+			if hedge && t.hedges != nil {
+				<-t.hedges
+			}
+			results <- &hedgedResult{
+				index: index,
+				res:   res,
+				err:   err,
+			}
+		}()
+	}
+	send(false)
+	pending := 1
+	timer := clock.After(t.getDelay())
+	for {
+		select {
+		case <-timer:
+			timer = nil
+			if t.acquire() {
+				send(true)
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if r.err != nil && pending > 0 {
+				// the other one may still succeed.
+				cancels[r.index]()
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != r.index {
+					cancel()
+				}
+			}
+			go drain(results, pending)
+			if r.err != nil {
+				cancels[r.index]()
+				return nil, r.err
+			}
+			t.observe(clock.Now().Sub(start))
+			// the context of the winner must be alive until the body is consumed.
+			r.res.Body = &cancelOnClose{
+				ReadCloser: r.res.Body,
+				cancel:     cancels[r.index],
+			}
+			return r.res, nil
+		}
+	}
+}
+
+// drain discards the responses of the canceled requests.
+func drain(results chan *hedgedResult, n int) {
+	for i := 0; i < n; i++ {
+		if r := <-results; r.res != nil {
+			r.res.Body.Close()
+		}
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (t *hedgedTransport) acquire() bool {
+	if t.hedges == nil {
+		return true
+	}
+	select {
+	case t.hedges <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *hedgedTransport) getDelay() time.Duration {
+	if t.window <= 0 {
+		return t.delay
+	}
+	t.mu.Lock()
+	if len(t.latencies) < t.window {
+		t.mu.Unlock()
+		return t.delay
+	}
+	latencies := make([]time.Duration, len(t.latencies))
+	copy(latencies, t.latencies)
+	t.mu.Unlock()
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	idx := int(math.Ceil(t.percentile/100*float64(len(latencies)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(latencies) {
+		idx = len(latencies) - 1
+	}
+	if latencies[idx] < t.delay {
+		return t.delay
+	}
+	return latencies[idx]
+}
+
+func (t *hedgedTransport) observe(d time.Duration) {
+	if t.window <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.latencies) < t.window {
+		t.latencies = append(t.latencies, d)
+		return
+	}
+	t.latencies[t.next] = d
+	t.next = (t.next + 1) % t.window
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
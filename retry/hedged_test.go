@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xnet/xhttp/xhttptest"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func Test_HedgedTransport(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	canceled := make(chan struct{})
+	xhttptest.UseStubServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&i, 1)
+			body, _ := ioutil.ReadAll(r.Body)
+			if n == 1 {
+				// the first one is slow and canceled by the hedge.
+				<-r.Context().Done()
+				close(canceled)
+				return
+			}
+			w.Write([]byte("hedged:" + string(body)))
+		}),
+		func(s *xhttptest.StubServer) {
+			client := s.Client(
+				map[string]string{
+					"http://example.com/": "/",
+				},
+				&http.Client{
+					Transport: NewHedgedTransport(http.DefaultTransport, 10*time.Millisecond),
+				},
+			)
+			req, _ := http.NewRequest("GET", "http://example.com/", strings.NewReader("body"))
+			resp, err := client.Do(req)
+			a.Nil(err)
+			a.EqInt(200, resp.StatusCode)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			a.EqStr("hedged:body", string(body))
+			select {
+			case <-canceled:
+				break
+			case <-time.After(time.Second):
+				t.Error("the slow request should be canceled")
+			}
+		},
+	)
+	a.EqInt(2, int(atomic.LoadInt32(&i)))
+}
+
+func Test_HedgedTransport_UnsafeMethod(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	xhttptest.UseStubServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&i, 1)
+			time.Sleep(50 * time.Millisecond)
+		}),
+		func(s *xhttptest.StubServer) {
+			client := s.Client(
+				map[string]string{
+					"http://example.com/": "/",
+				},
+				&http.Client{
+					Transport: NewHedgedTransport(http.DefaultTransport, time.Millisecond),
+				},
+			)
+			resp, err := client.Post("http://example.com/", "text/plain", strings.NewReader("body"))
+			a.Nil(err)
+			a.EqInt(200, resp.StatusCode)
+		},
+	)
+	a.EqInt(1, int(atomic.LoadInt32(&i)))
+}
+
+func Test_HedgedTransport_MaxConcurrentHedges(t *testing.T) {
+	a := assert.New(t)
+	var i int32
+	xhttptest.UseStubServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&i, 1)
+			time.Sleep(50 * time.Millisecond)
+		}),
+		func(s *xhttptest.StubServer) {
+			client := s.Client(
+				map[string]string{
+					"http://example.com/": "/",
+				},
+				&http.Client{
+					Transport: NewHedgedTransport(http.DefaultTransport, time.Millisecond, MaxConcurrentHedges(0)),
+				},
+			)
+			resp, err := client.Get("http://example.com/")
+			a.Nil(err)
+			a.EqInt(200, resp.StatusCode)
+		},
+	)
+	a.EqInt(1, int(atomic.LoadInt32(&i)))
+}
+
+func Test_HedgedTransport_Percentile(t *testing.T) {
+	a := assert.New(t)
+	tr := NewHedgedTransport(nil, 10*time.Millisecond, HedgeAtPercentile(90, 10)).(*hedgedTransport)
+	a.OK(tr.getDelay() == 10*time.Millisecond)
+	for i := 1; i <= 10; i++ {
+		tr.observe(time.Duration(i*10) * time.Millisecond)
+	}
+	a.OK(tr.getDelay() == 90*time.Millisecond, tr.getDelay())
+	// the oldest one is replaced.
+	tr.observe(200 * time.Millisecond)
+	a.OK(tr.getDelay() == 100*time.Millisecond, tr.getDelay())
+}
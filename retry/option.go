@@ -0,0 +1,113 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yssk22/go/x/xlog"
+)
+
+// LoggerKey is a key for logger in this package
+const LoggerKey = "retry"
+
+// AttemptHeaderName is a header name to send the attempt number by the HTTP transport.
+const AttemptHeaderName = "X-Retry-Attempt"
+
+// Option is a function to configure Do and NewHTTPTransport
+type Option func(*config) *config
+
+type config struct {
+	onRetry       []func(attempt int, err error, delay time.Duration)
+	attemptHeader bool
+}
+
+func newConfig(options []Option) *config {
+	c := &config{}
+	for _, f := range options {
+		c = f(c)
+	}
+	return c
+}
+
+// OnRetry returns an Option to call `f` before waiting for the next attempt.
+// For the HTTP transport, err is an *HTTPStatusError when the round trip succeeds with a retriable status.
+func OnRetry(f func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *config) *config {
+		c.onRetry = append(c.onRetry, f)
+		return c
+	}
+}
+
+// AttemptHeader returns an Option to send the attempt number by X-Retry-Attempt header from the HTTP transport
+// so that the downstream can correlate attempts in its logs.
+func AttemptHeader() Option {
+	return func(c *config) *config {
+		c.attemptHeader = true
+		return c
+	}
+}
+
+// Attempt returns the current attempt number (starting from 1) in the task of Do or the base transport of NewHTTPTransport.
+// It returns 0 if ctx is not under retries.
+func Attempt(ctx context.Context) int {
+	s := getState(ctx)
+	if s == nil {
+		return 0
+	}
+	return s.attempt
+}
+
+// Record is a log record emitted on each retry
+type Record struct {
+	Attempt int           `json:"attempt"`
+	Delay   time.Duration `json:"delay"`
+	Error   string        `json:"error,omitempty"`
+	Method  string        `json:"method,omitempty"`
+	URL     string        `json:"url,omitempty"`
+}
+
+func (r *Record) String() string {
+	var buff strings.Builder
+	fmt.Fprintf(&buff, "retry attempt=%d delay=%s", r.Attempt, r.Delay)
+	if r.Method != "" {
+		fmt.Fprintf(&buff, " request=\"%s %s\"", r.Method, r.URL)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&buff, " error=%q", r.Error)
+	}
+	return buff.String()
+}
+
+// HTTPStatusError is an error passed to OnRetry callbacks when the HTTP transport retries on the response status.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return e.Status
+}
+
+func (c *config) notify(ctx context.Context, record *Record, err error) {
+	if err != nil {
+		record.Error = err.Error()
+	}
+	_, logger := xlog.WithContextAndKey(ctx, "retry", LoggerKey)
+	logger.Info(record)
+	for _, f := range c.onRetry {
+		f(record.Attempt, err, record.Delay)
+	}
+}
+
+func httpError(res *http.Response, err error) error {
+	if err != nil || res == nil {
+		return err
+	}
+	return &HTTPStatusError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+	}
+}
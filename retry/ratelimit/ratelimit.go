@@ -0,0 +1,260 @@
+// Package ratelimit provides the client-side rate limiter by token buckets.
+//
+// A Limiter holds up to `burst` tokens and refills them at `rate` tokens per second.
+// Each call takes a token and waits when the bucket is empty. The rate can be scaled
+// down dynamically by the usage reported by the downstream (see Adjuster).
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yssk22/go/x/xtime"
+)
+
+// Inf is a rate that does not limit anything.
+var Inf = math.Inf(1)
+
+// Every converts the interval between events to the rate.
+func Every(d time.Duration) float64 {
+	if d <= 0 {
+		return Inf
+	}
+	return float64(time.Second) / float64(d)
+}
+
+// Option is a function to configure *Limiter
+type Option func(*config) *config
+
+type config struct {
+	burst       int
+	minFactor   float64
+	clock       xtime.Clock
+	idleTimeout time.Duration
+}
+
+func newConfig(options []Option) *config {
+	c := &config{
+		burst:       1,
+		minFactor:   0.05,
+		clock:       xtime.SystemClock,
+		idleTimeout: DefaultIdleTimeout,
+	}
+	for _, f := range options {
+		c = f(c)
+	}
+	if c.burst < 1 {
+		c.burst = 1
+	}
+	return c
+}
+
+// DefaultIdleTimeout is the default duration to keep an unused limiter in a Group.
+const DefaultIdleTimeout = 10 * time.Minute
+
+// Burst returns an Option to set the size of the bucket. The default is 1.
+func Burst(n int) Option {
+	return func(c *config) *config {
+		c.burst = n
+		return c
+	}
+}
+
+// MinFactor returns an Option to set the lower bound of the factor set by SetFactor. The default is 0.05.
+func MinFactor(f float64) Option {
+	return func(c *config) *config {
+		c.minFactor = f
+		return c
+	}
+}
+
+// IdleTimeout returns an Option for Group to evict the limiters not used for `d`, such as the ones for expired tokens.
+// 0 never evicts the limiters. The default is DefaultIdleTimeout.
+func IdleTimeout(d time.Duration) Option {
+	return func(c *config) *config {
+		c.idleTimeout = d
+		return c
+	}
+}
+
+// Clock returns an Option to set the clock, mainly for tests.
+func Clock(clock xtime.Clock) Option {
+	return func(c *config) *config {
+		c.clock = clock
+		return c
+	}
+}
+
+// Limiter is a token bucket rate limiter.
+type Limiter struct {
+	config *config
+	rate   float64
+
+	mu     sync.Mutex
+	factor float64
+	tokens float64
+	last   time.Time
+}
+
+// New returns a new *Limiter that allows `rate` calls per second. The bucket starts full.
+func New(rate float64, options ...Option) *Limiter {
+	return newLimiter(rate, newConfig(options))
+}
+
+func newLimiter(rate float64, c *config) *Limiter {
+	return &Limiter{
+		config: c,
+		rate:   rate,
+		factor: 1,
+		tokens: float64(c.burst),
+		last:   c.clock.Now(),
+	}
+}
+
+// Rate returns the current rate scaled by the factor.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.effectiveRate()
+}
+
+// Factor returns the current factor.
+func (l *Limiter) Factor() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.factor
+}
+
+// SetFactor scales the rate by `f` in [MinFactor, 1].
+func (l *Limiter) SetFactor(f float64) {
+	if f > 1 {
+		f = 1
+	}
+	if f < l.config.minFactor {
+		f = l.config.minFactor
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.config.clock.Now())
+	l.factor = f
+}
+
+// Allow takes a token if available and returns true, otherwise returns false without waiting.
+func (l *Limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.take()
+	return ok
+}
+
+// Wait waits until a token is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		wait, ok := l.take()
+		l.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.config.clock.After(wait):
+			break
+		}
+	}
+}
+
+// take takes a token or returns the duration to wait for the next token.
+func (l *Limiter) take() (time.Duration, bool) {
+	rate := l.effectiveRate()
+	if math.IsInf(rate, 1) {
+		return 0, true
+	}
+	l.refill(l.config.clock.Now())
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, true
+	}
+	if rate <= 0 {
+		return time.Duration(math.MaxInt64), false
+	}
+	wait := time.Duration(math.Ceil((1 - l.tokens) / rate * float64(time.Second)))
+	return wait, false
+}
+
+func (l *Limiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	l.tokens = math.Min(float64(l.config.burst), l.tokens+elapsed.Seconds()*l.effectiveRate())
+}
+
+func (l *Limiter) effectiveRate() float64 {
+	return l.rate * l.factor
+}
+
+// Group is a set of limiters keyed by names, created on demand with the same rate and options.
+// The limiters not used for IdleTimeout are evicted so that the group does not grow by the keys such as access tokens.
+type Group struct {
+	rate      float64
+	config    *config
+	mu        sync.Mutex
+	limiters  map[string]*groupEntry
+	lastSweep time.Time
+}
+
+type groupEntry struct {
+	limiter  *Limiter
+	lastUsed time.Time
+}
+
+// NewGroup returns a new *Group
+func NewGroup(rate float64, options ...Option) *Group {
+	c := newConfig(options)
+	return &Group{
+		rate:      rate,
+		config:    c,
+		limiters:  make(map[string]*groupEntry),
+		lastSweep: c.clock.Now(),
+	}
+}
+
+// Get returns a *Limiter for `key`
+func (g *Group) Get(key string) *Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.config.clock.Now()
+	g.sweep(now)
+	e, ok := g.limiters[key]
+	if !ok {
+		e = &groupEntry{limiter: newLimiter(g.rate, g.config)}
+		g.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
+
+// Len returns the number of limiters in the group.
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.limiters)
+}
+
+// sweep evicts the idle limiters at most once per the idle timeout. g.mu must be held.
+func (g *Group) sweep(now time.Time) {
+	if g.config.idleTimeout <= 0 || now.Sub(g.lastSweep) < g.config.idleTimeout {
+		return
+	}
+	g.lastSweep = now
+	for key, e := range g.limiters {
+		if now.Sub(e.lastUsed) >= g.config.idleTimeout {
+			delete(g.limiters, key)
+		}
+	}
+}
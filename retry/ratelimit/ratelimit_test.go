@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+func TestLimiter(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := New(2, Burst(3), Clock(clock))
+	a.OK(l.Allow())
+	a.OK(l.Allow())
+	a.OK(l.Allow())
+	a.OK(!l.Allow())
+	clock.Advance(500 * time.Millisecond)
+	a.OK(l.Allow())
+	a.OK(!l.Allow())
+	// never exceeds the burst
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		a.OK(l.Allow())
+	}
+	a.OK(!l.Allow())
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	clock.AutoAdvance = true
+	l := New(Every(100*time.Millisecond), Clock(clock))
+	for i := 0; i < 3; i++ {
+		a.Nil(l.Wait(context.Background()))
+	}
+	slept := clock.Slept()
+	a.EqInt(2, len(slept))
+	a.OK(slept[0] == 100*time.Millisecond)
+	a.OK(slept[1] == 100*time.Millisecond)
+}
+
+func TestLimiter_WaitCanceled(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := New(1, Clock(clock))
+	a.OK(l.Allow())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	a.OK(<-done == context.Canceled)
+}
+
+func TestLimiter_SetFactor(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := New(10, Clock(clock))
+	l.SetFactor(0.5)
+	a.OK(l.Rate() == 5)
+	l.SetFactor(0)
+	a.OK(l.Factor() == 0.05)
+	l.SetFactor(2)
+	a.OK(l.Factor() == 1)
+}
+
+func TestGroup(t *testing.T) {
+	a := assert.New(t)
+	g := NewGroup(1)
+	a.OK(g.Get("a") == g.Get("a"))
+	a.OK(g.Get("a") != g.Get("b"))
+}
+
+func TestGroup_IdleTimeout(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := NewGroup(1, Clock(clock), IdleTimeout(time.Minute))
+	la := g.Get("a")
+	g.Get("b")
+	a.EqInt(2, g.Len())
+
+	clock.Advance(30 * time.Second)
+	a.OK(la == g.Get("a"))
+	clock.Advance(30 * time.Second)
+	// "b" is idle for a minute
+	a.OK(la == g.Get("a"))
+	a.EqInt(1, g.Len())
+
+	clock.Advance(time.Minute)
+	g.Get("c")
+	a.EqInt(1, g.Len())
+	a.OK(la != g.Get("a"))
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// Adjuster is a function to adjust the limiter by the response from the downstream.
+type Adjuster func(*Limiter, *http.Response)
+
+// FacebookAppUsage returns an Adjuster to scale the rate by the X-App-Usage header from the Graph API.
+// The header reports the usage in percentages and the rate is scaled by (100 - max(usage)) / 100.
+func FacebookAppUsage() Adjuster {
+	return func(l *Limiter, resp *http.Response) {
+		v := resp.Header.Get("X-App-Usage")
+		if v == "" {
+			return
+		}
+		var usage struct {
+			CallCount    float64 `json:"call_count"`
+			TotalTime    float64 `json:"total_time"`
+			TotalCPUTime float64 `json:"total_cputime"`
+		}
+		if err := json.Unmarshal([]byte(v), &usage); err != nil {
+			return
+		}
+		max := usage.CallCount
+		if usage.TotalTime > max {
+			max = usage.TotalTime
+		}
+		if usage.TotalCPUTime > max {
+			max = usage.TotalCPUTime
+		}
+		l.SetFactor((100 - max) / 100)
+	}
+}
+
+// ByHost is a key function to use a bucket per host. This is the default.
+func ByHost(req *http.Request) string {
+	return req.URL.Host
+}
+
+// ByAccessToken is a key function to use a bucket per host and access token.
+// The token is taken from the access_token query parameter or the Authorization header and is hashed in the key.
+func ByAccessToken(req *http.Request) string {
+	token := req.URL.Query().Get("access_token")
+	if token == "" {
+		token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		return req.URL.Host
+	}
+	h := fnv.New64a()
+	h.Write([]byte(token))
+	return fmt.Sprintf("%s#%x", req.URL.Host, h.Sum64())
+}
+
+// TransportOption is a function to configure the transport
+type TransportOption func(*transport) *transport
+
+// KeyFunc returns a TransportOption to select the limiter for the request. The default is ByHost.
+func KeyFunc(f func(*http.Request) string) TransportOption {
+	return func(t *transport) *transport {
+		t.key = f
+		return t
+	}
+}
+
+// Adjust returns a TransportOption to adjust the limiter by responses.
+func Adjust(adjusters ...Adjuster) TransportOption {
+	return func(t *transport) *transport {
+		t.adjusters = append(t.adjusters, adjusters...)
+		return t
+	}
+}
+
+type transport struct {
+	base      http.RoundTripper
+	group     *Group
+	key       func(*http.Request) string
+	adjusters []Adjuster
+}
+
+// NewTransport returns a http.RoundTripper that waits for a token of the limiter in `group` before each request.
+// It can be a base of retry.NewHTTPTransport so that every retry attempt also takes a token.
+func NewTransport(base http.RoundTripper, group *Group, options ...TransportOption) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base:  base,
+		group: group,
+		key:   ByHost,
+	}
+	for _, f := range options {
+		t = f(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper#RoundTrip
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.group.Get(t.key(req))
+	if err := l.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("could not wait for the rate limit (%s): %w", req.URL.Host, err)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		for _, adjust := range t.adjusters {
+			adjust(l, resp)
+		}
+	}
+	return resp, err
+}
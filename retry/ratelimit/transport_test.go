@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/retry"
+	"github.com/yssk22/go/x/xtesting/assert"
+	"github.com/yssk22/go/x/xtime"
+)
+
+type stubTransport func(*http.Request) (*http.Response, error)
+
+func (f stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       http.NoBody,
+	}
+}
+
+func TestTransport(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	group := NewGroup(1, Clock(clock))
+	var i = 0
+	client := &http.Client{
+		Transport: NewTransport(stubTransport(func(req *http.Request) (*http.Response, error) {
+			i++
+			return newResponse(200, http.Header{
+				"X-App-Usage": []string{`{"call_count":80,"total_time":25,"total_cputime":10}`},
+			}), nil
+		}), group, Adjust(FacebookAppUsage())),
+	}
+	resp, err := client.Get("http://graph.facebook.com/me")
+	a.Nil(err)
+	a.EqInt(200, resp.StatusCode)
+	l := group.Get("graph.facebook.com")
+	a.OK(fmt.Sprintf("%.2f", l.Factor()) == "0.20", l.Factor())
+
+	// no token available
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", "http://graph.facebook.com/me", nil)
+	_, err = client.Do(req.WithContext(ctx))
+	a.NotNil(err)
+	a.OK(errors.Is(err, context.DeadlineExceeded))
+	a.EqInt(1, i)
+
+	// other hosts have their own buckets.
+	_, err = client.Get("http://example.com/")
+	a.Nil(err)
+	a.EqInt(2, i)
+}
+
+func TestTransport_WithRetry(t *testing.T) {
+	a := assert.New(t)
+	clock := xtime.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	clock.AutoAdvance = true
+	group := NewGroup(Every(time.Second), Clock(clock))
+	var i = 0
+	base := NewTransport(stubTransport(func(req *http.Request) (*http.Response, error) {
+		i++
+		if i < 3 {
+			return newResponse(500, nil), nil
+		}
+		return newResponse(200, nil), nil
+	}), group)
+	client := &http.Client{
+		Transport: retry.NewHTTPTransport(
+			base,
+			retry.HTTPAnd(retry.HTTPRetryUntil(5), retry.HTTPRetryOnServerErrorOrTooManyRequests()),
+			retry.HTTPConstBackoff(0),
+		),
+	}
+	resp, err := client.Get("http://example.com/")
+	a.Nil(err)
+	a.EqInt(200, resp.StatusCode)
+	a.EqInt(3, i)
+	// each attempt takes a token
+	a.EqInt(2, len(clock.Slept()))
+}
+
+func TestByAccessToken(t *testing.T) {
+	a := assert.New(t)
+	req1, _ := http.NewRequest("GET", "http://graph.facebook.com/me?access_token=token1", nil)
+	req2, _ := http.NewRequest("GET", "http://graph.facebook.com/me?access_token=token2", nil)
+	req3, _ := http.NewRequest("GET", "http://graph.facebook.com/me", nil)
+	req3.Header.Set("Authorization", "Bearer token1")
+	a.OK(ByAccessToken(req1) != ByAccessToken(req2))
+	a.OK(ByAccessToken(req1) == ByAccessToken(req3))
+	a.EqStr("graph.facebook.com", ByHost(req1))
+}
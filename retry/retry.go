@@ -5,14 +5,21 @@ import (
 	"time"
 
 	"context"
+
+	"github.com/yssk22/go/x/xcontext"
+	"github.com/yssk22/go/x/xtime"
 )
 
 // Do retries `task` function until it returns nil error.
 // If task return an error, checker checks if the retry is needed afeter waitng by waiter.
-func Do(ctx context.Context, task func(context.Context) error, backoff Backoff, checker Checker) error {
+// The attempt number is available by Attempt(ctx) in the task.
+func Do(ctx context.Context, task func(context.Context) error, backoff Backoff, checker Checker, options ...Option) error {
 	var err error
 	var attempt int
+	c := newConfig(options)
+	ctx, s := withState(ctx)
 	for {
+		s.attempt = attempt + 1
 		err = task(ctx)
 		attempt++
 		if err == nil {
@@ -21,16 +28,48 @@ func Do(ctx context.Context, task func(context.Context) error, backoff Backoff,
 		if !checker.NeedRetry(ctx, attempt, err) {
 			return err
 		}
-		ticker := time.NewTicker(backoff.Calc(ctx, attempt))
+		delay := backoff.Calc(ctx, attempt)
+		s.lastDelay = delay
+		c.notify(ctx, &Record{
+			Attempt: attempt,
+			Delay:   delay,
+		}, err)
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("retry canceled: %v", ctx.Err())
-		case <-ticker.C:
+		case <-clock.After(delay):
 			break
 		}
 	}
 }
 
+// clock is used for backoff waits and time checks and can be replaced in tests.
+var clock = xtime.SystemClock
+
+// state is a retry state shared in a series of attempts
+type state struct {
+	start     time.Time
+	attempt   int
+	lastDelay time.Duration
+}
+
+var stateContextKey = xcontext.NewKey("state")
+
+func withState(ctx context.Context) (context.Context, *state) {
+	s := &state{
+		start: clock.Now(),
+	}
+	return context.WithValue(ctx, stateContextKey, s), s
+}
+
+func getState(ctx context.Context) *state {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(stateContextKey).(*state)
+	return s
+}
+
 // Backoff is an interface to implement backoff algorithm
 type Backoff interface {
 	Calc(context.Context, int) time.Duration
@@ -69,7 +108,7 @@ type until struct {
 }
 
 func (u *until) NeedRetry(ctx context.Context, attempt int, err error) bool {
-	return time.Now().Before(u.t)
+	return clock.Now().Before(u.t)
 }
 
 // MaxRetries returns a Checker to check the number of retries is less than max.
@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +15,7 @@ type httpTransport struct {
 	Base    http.RoundTripper
 	Cond    HTTPRetryCond
 	Backoff HTTPBackoff
+	config  *config
 }
 
 // HTTPBackoff is an http version of Backoff
@@ -27,23 +29,39 @@ type HTTPRetryCond interface {
 }
 
 // NewHTTPTransport returns a new http.RoundTripper instance for given checker and backoff configurations on top of base http.RoundTripper
-func NewHTTPTransport(base http.RoundTripper, cond HTTPRetryCond, backoff HTTPBackoff) http.RoundTripper {
+func NewHTTPTransport(base http.RoundTripper, cond HTTPRetryCond, backoff HTTPBackoff, options ...Option) http.RoundTripper {
 	return &httpTransport{
 		Base:    base,
 		Cond:    cond,
 		Backoff: backoff,
+		config:  newConfig(options),
 	}
 }
 
 // RoundTrip implements http.Transport#RoundTrip
+// If the response status is 429 or 503 with Retry-After header, the transport waits for
+// the duration specified by the header if it is longer than the one calculated by the backoff.
 func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	var attempt int
 	// request body should be buffered on memory since it is consumed by RoundTrip.
-	reqBody, err := t.bufferBody(req)
+	reqBody, err := bufferBody(req)
 	if err != nil {
 		return nil, fmt.Errorf("could not allocate request body for *retry.HTTPTransport: %v", err)
 	}
+	ctx, s := withState(req.Context())
+	req = req.WithContext(ctx)
+	if t.config.attemptHeader {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		} else {
+			req.Header = req.Header.Clone()
+		}
+	}
 	for {
+		s.attempt = attempt + 1
+		if t.config.attemptHeader {
+			req.Header.Set(AttemptHeaderName, strconv.Itoa(s.attempt))
+		}
 		if reqBody != nil {
 			if _, serr := reqBody.Seek(0, 0); serr != nil {
 				return nil, fmt.Errorf("could not reset request body for *retry.HTTPTransport: %v", err)
@@ -55,22 +73,71 @@ func (t *httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		if !t.Cond.NeedRetry(attempt, req, res, err) {
 			return res, err
 		}
+		delay := t.Backoff.Calc(attempt, req, res, err)
+		if retryAfter, ok := getRetryAfter(res); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+		s.lastDelay = delay
+		t.config.notify(ctx, &Record{
+			Attempt: attempt,
+			Delay:   delay,
+			Method:  req.Method,
+			URL:     getLogURL(req),
+		}, httpError(res, err))
 		// discard body content for retry.
 		if res != nil && res.Body != nil {
 			io.Copy(ioutil.Discard, res.Body)
 			res.Body.Close()
 		}
-		ticker := time.NewTicker(t.Backoff.Calc(attempt, req, res, err))
 		select {
 		case <-req.Cancel:
 			return nil, fmt.Errorf("request canceled")
-		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("request canceled: %v", ctx.Err())
+		case <-clock.After(delay):
 			break
 		}
 	}
 }
 
-func (t *httpTransport) bufferBody(req *http.Request) (*bytes.Reader, error) {
+// getRetryAfter returns the duration specified by Retry-After header on 429 or 503 responses.
+func getRetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0, false
+		}
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(clock.Now())
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// getLogURL returns the request url without the query string not to log credentials such as access tokens.
+func getLogURL(req *http.Request) string {
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+	return u.String()
+}
+
+// bufferBody reads the request body on memory so that it can be sent multiple times.
+func bufferBody(req *http.Request) (*bytes.Reader, error) {
 	if req.Body == nil {
 		return nil, nil
 	}
@@ -99,6 +166,26 @@ func (b *httpConstBackoff) Calc(int, *http.Request, *http.Response, error) time.
 	return b.interval
 }
 
+// HTTPExponentialBackoff is a http version of ExponentialBackoff
+func HTTPExponentialBackoff(min, max time.Duration, jitter Jitter) HTTPBackoff {
+	return &httpExponentialBackoff{
+		exponentialBackoff: &exponentialBackoff{
+			min:    min,
+			max:    max,
+			jitter: jitter,
+		},
+	}
+}
+
+type httpExponentialBackoff struct {
+	*exponentialBackoff
+}
+
+// Calc implements HTTPBackoff#Calc()
+func (b *httpExponentialBackoff) Calc(attempt int, req *http.Request, resp *http.Response, err error) time.Duration {
+	return b.calc(attempt, getState(req.Context()))
+}
+
 // HTTPAnd is a AND combination of multiple HTTPRetryCond instances.
 func HTTPAnd(checkers ...HTTPRetryCond) HTTPRetryCond {
 	return &httpAnd{
@@ -145,6 +232,30 @@ func HTTPRetryOnServerError() HTTPRetryCond {
 	)
 }
 
+// HTTPRetryOnServerErrorOrTooManyRequests is like HTTPRetryOnServerError but also needs retries when http status code is 429
+func HTTPRetryOnServerErrorOrTooManyRequests() HTTPRetryCond {
+	return HTTPRetryIf(
+		func(resp *http.Response) bool {
+			return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		},
+	)
+}
+
+// HTTPRetryWithin returns a HTTPRetryCond that needs retries while the total elapsed time since the first attempt is less than `d`.
+func HTTPRetryWithin(d time.Duration) HTTPRetryCond {
+	return &httpRetryWithin{
+		d: d,
+	}
+}
+
+type httpRetryWithin struct {
+	d time.Duration
+}
+
+func (c *httpRetryWithin) NeedRetry(attempt int, req *http.Request, resp *http.Response, err error) bool {
+	return withinBudget(getState(req.Context()), c.d)
+}
+
 // HTTPRetryIf returns a HTTPRetryCond that checks *http.Response for retries
 func HTTPRetryIf(f func(resp *http.Response) bool) HTTPRetryCond {
 	return &httpRetryIf{
@@ -2,7 +2,7 @@ package main
 
 import (
 	"bytes"
-	"flag"
+	"context"
 	"fmt"
 	"go/ast"
 	"go/build"
@@ -19,26 +19,31 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/yssk22/go/cli"
 	"github.com/yssk22/go/x/xstrings"
 )
 
 const defaultOutput = "enum_helper.go"
 
-var (
-	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
-	output    = flag.String("output", "", "output file name; default srcdir/<type>_enum.go.go")
-)
+var opts struct {
+	TypeNames string `config:"type" usage:"comma-separated list of type names" required:"true"`
+	Output    string `config:"output" usage:"output file name; default srcdir/<type>_enum.go"`
+}
 
 func main() {
 	log.SetPrefix("[enum] ")
 	log.SetFlags(0)
-	flag.Parse()
-	if len(*typeNames) == 0 {
-		flag.Usage()
-		os.Exit(2)
-	}
-	types := strings.Split(*typeNames, ",")
-	args := flag.Args()
+	cli.Main(&cli.Command{
+		Name:      "enum",
+		Usage:     "generate the helper functions for enum types",
+		ArgsUsage: "[dir...]",
+		Flags:     &opts,
+		Run:       run,
+	})
+}
+
+func run(ctx context.Context, args []string) error {
+	types := strings.Split(opts.TypeNames, ",")
 	if len(args) == 0 {
 		args = []string{"."}
 	}
@@ -64,15 +69,15 @@ func main() {
 			continue
 		}
 		src := g.format()
-		outputName := *output
+		outputName := opts.Output
 		if outputName == "" {
 			outputName = filepath.Join(directory, fmt.Sprintf("%s_enum.go", xstrings.ToSnakeCase(types[0])))
 		}
-		err := ioutil.WriteFile(outputName, src, 0644)
-		if err != nil {
-			log.Fatalf("writing output: %s", err)
+		if err := ioutil.WriteFile(outputName, src, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
 		}
 	}
+	return nil
 }
 
 type Generator struct {
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"flag"
+	"context"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/yssk22/go/cli"
 	"github.com/yssk22/go/generator"
 	"github.com/yssk22/go/generator/enum"
 	api "github.com/yssk22/go/web/api/generator"
@@ -16,16 +17,23 @@ import (
 	"github.com/yssk22/go/x/xstrings"
 )
 
-var (
-	annotation = flag.String("a", "", "annotation name to generate the sources")
-)
-
+var opts struct {
+	Annotation string `config:"a" usage:"comma-separated annotation names to generate the sources"`
+}
 
 func main() {
 	log.SetPrefix("[gensource] ")
 	log.SetFlags(0)
-	flag.Parse()
-	args := flag.Args()
+	cli.Main(&cli.Command{
+		Name:      "gensource",
+		Usage:     "generate the sources from the annotations",
+		ArgsUsage: "[dir...]",
+		Flags:     &opts,
+		Run:       run,
+	})
+}
+
+func run(ctx context.Context, args []string) error {
 	if len(args) == 0 {
 		args = []string{"."}
 	}
@@ -36,10 +44,10 @@ func main() {
 		enum.NewGenerator(),
 		typed.NewGenerator(),
 	}
-	anns := xstrings.SplitAndTrim(*annotation, ",")
+	anns := xstrings.SplitAndTrim(opts.Annotation, ",")
 	generators = slice.Filter(generators, func(i int, g interface{}) bool{
 		gena := g.(generator.Generator).GetAnnotationSymbol()
-		if *annotation == "" {
+		if opts.Annotation == "" {
 			log.Printf("%s: yes\n", gena)
 			return false
 		}
@@ -56,6 +64,7 @@ func main() {
 	for _, dir := range args {
 		runDirectory(runner, dir, false)
 	}
+	return nil
 }
 
 func runDirectory(runner *generator.Runner, dir string, recursive bool) {
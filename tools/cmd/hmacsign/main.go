@@ -1,39 +1,42 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
-	"flag"
 	"fmt"
-	"os"
 
+	"github.com/yssk22/go/cli"
 	"github.com/yssk22/go/x/xcrypto/xhmac"
 )
 
-var (
-	unsign = flag.Bool("u", false, "unsign the key string")
-	key    = flag.String("k", "", "hmac key string")
-)
+var opts struct {
+	Unsign bool   `config:"u" usage:"unsign the key string"`
+	Key    string `config:"k" usage:"hmac key string" required:"true"`
+}
 
 func main() {
-	flag.Parse()
-	if *key == "" {
-		fmt.Fprintf(os.Stderr, "-k [key] must be specified\n")
-		os.Exit(1)
-	}
-	hmac := xhmac.NewBase64([]byte(*key), sha256.New)
-	args := flag.Args()
+	cli.Main(&cli.Command{
+		Name:      "hmacsign",
+		Usage:     "sign or unsign a string by HMAC-SHA256",
+		ArgsUsage: "<string>",
+		Flags:     &opts,
+		Run:       run,
+	})
+}
+
+func run(ctx context.Context, args []string) error {
 	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "no string is specified\n")
-		os.Exit(1)
+		return &cli.UsageError{Err: fmt.Errorf("no string is specified")}
 	}
-	if *unsign {
+	hmac := xhmac.NewBase64([]byte(opts.Key), sha256.New)
+	if opts.Unsign {
 		str, err := hmac.UnsignString(args[0])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+			return err
 		}
 		fmt.Println(str)
 	} else {
 		fmt.Println(hmac.SignString(args[0]))
 	}
+	return nil
 }
@@ -0,0 +1,129 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/yssk22/go/web/response"
+)
+
+// group is a Router to add routes under the prefix with the group scoped middleware.
+type group struct {
+	root       *defaultRouter
+	parent     *group
+	prefix     string
+	middleware *handlerPipeline
+}
+
+func newGroup(root *defaultRouter, parent *group, prefix string, middleware ...Handler) *group {
+	g := &group{
+		root:       root,
+		parent:     parent,
+		prefix:     prefix,
+		middleware: &handlerPipeline{},
+	}
+	if parent != nil {
+		g.prefix = joinPath(parent.prefix, prefix)
+	}
+	g.middleware.Append(middleware...)
+	return g
+}
+
+// Use adds middleware handlers to process on the requests for the routes in the group.
+func (g *group) Use(handlers ...Handler) {
+	g.middleware.Append(handlers...)
+}
+
+// All adds handlers for "GET|POST|PUT|PATCH|DELETE {prefix}{pattern}" requests
+func (g *group) All(pattern string, handlers ...Handler) {
+	for _, method := range allMethods {
+		g.addRoute(method, pattern, handlers...)
+	}
+}
+
+// Get adds handlers for "GET {prefix}{pattern}" requests
+func (g *group) Get(pattern string, handlers ...Handler) {
+	g.addRoute("GET", pattern, handlers...)
+}
+
+// Post adds handlers for "POST {prefix}{pattern}" requests
+func (g *group) Post(pattern string, handlers ...Handler) {
+	g.addRoute("POST", pattern, handlers...)
+}
+
+// Put adds handlers for "PUT {prefix}{pattern}" requests
+func (g *group) Put(pattern string, handlers ...Handler) {
+	g.addRoute("PUT", pattern, handlers...)
+}
+
+// Patch adds handlers for "PATCH {prefix}{pattern}" requests
+func (g *group) Patch(pattern string, handlers ...Handler) {
+	g.addRoute("PATCH", pattern, handlers...)
+}
+
+// Delete adds handlers for "DELETE {prefix}{pattern}" requests
+func (g *group) Delete(pattern string, handlers ...Handler) {
+	g.addRoute("DELETE", pattern, handlers...)
+}
+
+// Head adds handlers for "HEAD {prefix}{pattern}" requests
+func (g *group) Head(pattern string, handlers ...Handler) {
+	g.addRoute("HEAD", pattern, handlers...)
+}
+
+// Options adds handlers for "OPTIONS {prefix}{pattern}" requests
+func (g *group) Options(pattern string, handlers ...Handler) {
+	g.addRoute("OPTIONS", pattern, handlers...)
+}
+
+// Group returns a nested group
+func (g *group) Group(prefix string, middleware ...Handler) Router {
+	return newGroup(g.root, g, prefix, middleware...)
+}
+
+// Mount is like Router#Mount but the group middleware are processed before `h`.
+func (g *group) Mount(prefix string, h http.Handler) {
+	g.root.mount(joinPath(g.prefix, prefix), h, g.handlers()...)
+}
+
+// URL builds the path of the named route by the root router.
+func (g *group) URL(name string, params ...interface{}) (string, error) {
+	return g.root.URL(name, params...)
+}
+
+// RouteTable returns the route table of the root router.
+func (g *group) RouteTable() map[string]string {
+	return g.root.RouteTable()
+}
+
+// ServeHTTP serves the request by the root router.
+func (g *group) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.root.ServeHTTP(w, req)
+}
+
+// addRoute adds the handlers as a unit scoped by the group middleware so that the middleware always guard
+// the handlers, even if the route is also registered by the root or other groups.
+func (g *group) addRoute(method string, pattern string, handlers ...Handler) {
+	var named, rest []Handler
+	for _, h := range handlers {
+		if _, ok := h.(*namedHandler); ok {
+			named = append(named, h)
+		} else {
+			rest = append(rest, h)
+		}
+	}
+	scoped := &handlerPipeline{}
+	scoped.Append(g.handlers()...)
+	scoped.Append(rest...)
+	g.root.addRoute(method, joinPath(g.prefix, pattern), append(named, HandlerFunc(scoped.Process))...)
+}
+
+// handlers returns the handlers to process the middleware from the outermost group.
+func (g *group) handlers() []Handler {
+	var handlers []Handler
+	if g.parent != nil {
+		handlers = g.parent.handlers()
+	}
+	return append(handlers, HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return g.middleware.Process(req, next)
+	}))
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/web/response"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func textHandler(s string) Handler {
+	return HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return response.NewText(req.Context(), s+req.Params.GetStringOr("id", ""))
+	})
+}
+
+func appendState(state *[]string, s string) Handler {
+	return HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		*state = append(*state, s)
+		return next(req)
+	})
+}
+
+func serve(router http.Handler, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter_Group(t *testing.T) {
+	a := assert.New(t)
+	var state []string
+	router := NewRouter(nil)
+	router.Use(appendState(&state, "root"))
+	router.Get("/", textHandler("index"))
+	api := router.Group("/api", appendState(&state, "api"))
+	api.Get("/users/:id", textHandler("user-"))
+	api.Get("/users/:id", textHandler("never reached"))
+	admin := api.Group("/admin/")
+	admin.Use(appendState(&state, "admin"))
+	admin.Post("/users/:id", textHandler("admin-user-"))
+
+	w := serve(router, "GET", "/")
+	a.EqStr("index", w.Body.String())
+	a.EqStr("root", strings.Join(state, ">"))
+
+	state = nil
+	w = serve(router, "GET", "/api/users/1")
+	a.EqStr("user-1", w.Body.String())
+	a.EqStr("root>api", strings.Join(state, ">"))
+
+	state = nil
+	w = serve(router, "POST", "/api/admin/users/2")
+	a.EqStr("admin-user-2", w.Body.String())
+	a.EqStr("root>api>admin", strings.Join(state, ">"))
+
+	// group middleware is not processed for unmatched routes
+	state = nil
+	w = serve(router, "GET", "/api/unknown")
+	a.EqInt(http.StatusNotFound, w.Code)
+	a.EqStr("root", strings.Join(state, ">"))
+
+	w = serve(router, "GET", "/__debug__/routes")
+	a.OK(strings.Contains(w.Body.String(), "GET /api/users/:id\n"), w.Body.String())
+	a.OK(strings.Contains(w.Body.String(), "POST /api/admin/users/:id\n"), w.Body.String())
+}
+
+func TestRouter_Group_sharedRoute(t *testing.T) {
+	a := assert.New(t)
+	var state []string
+	router := NewRouter(nil)
+	router.Get("/admin/x", appendState(&state, "root"))
+	admin := router.Group("/admin", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		state = append(state, "auth")
+		return response.NewTextWithStatus(req.Context(), "forbidden", response.HTTPStatusForbidden)
+	}))
+	admin.Get("/x", textHandler("admin"))
+
+	// the group middleware guard the group handlers even if the route is registered by the root.
+	w := serve(router, "GET", "/admin/x")
+	a.EqInt(http.StatusForbidden, w.Code)
+	a.EqStr("root>auth", strings.Join(state, ">"))
+}
+
+func TestRouter_Mount(t *testing.T) {
+	a := assert.New(t)
+	var state []string
+	sub := NewRouter(nil)
+	sub.Use(appendState(&state, "sub"))
+	sub.Get("/", textHandler("sub-index"))
+	sub.Get("/items/:id", textHandler("item-"))
+
+	router := NewRouter(nil)
+	router.Use(appendState(&state, "root"))
+	router.Mount("/sub", sub)
+	group := router.Group("/group", appendState(&state, "group"))
+	group.Mount("/http", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(req.URL.Path))
+	}))
+
+	w := serve(router, "GET", "/sub/items/1")
+	a.EqStr("item-1", w.Body.String())
+	a.EqStr("root>sub", strings.Join(state, ">"))
+
+	w = serve(router, "GET", "/sub")
+	a.EqStr("sub-index", w.Body.String())
+	w = serve(router, "GET", "/sub/")
+	a.EqStr("sub-index", w.Body.String())
+	w = serve(router, "GET", "/sub/unknown")
+	a.EqInt(http.StatusNotFound, w.Code)
+
+	state = nil
+	w = serve(router, "POST", "/group/http/path/to")
+	a.EqInt(http.StatusTeapot, w.Code)
+	a.EqStr("/path/to", w.Body.String())
+	a.EqStr("root>group", strings.Join(state, ">"))
+
+	w = serve(router, "GET", "/__debug__/routes")
+	a.OK(strings.Contains(w.Body.String(), "GET /sub/items/:id\n"), w.Body.String())
+	a.OK(strings.Contains(w.Body.String(), "GET /group/http/*\n"), w.Body.String())
+	a.OK(!strings.Contains(w.Body.String(), "GET /sub/*\n"), w.Body.String())
+}
+
+func TestRouter_MountGroup(t *testing.T) {
+	a := assert.New(t)
+	var state []string
+	sub := NewRouter(nil)
+	sub.Use(appendState(&state, "sub"))
+	api := sub.Group("/api", appendState(&state, "api"))
+	api.Get("/", textHandler("api-index"))
+	api.Get("/users/:id", textHandler("user-"))
+
+	router := NewRouter(nil)
+	router.Use(appendState(&state, "root"))
+	router.Mount("/v1", api)
+
+	w := serve(router, "GET", "/v1/users/1")
+	a.EqStr("user-1", w.Body.String())
+	a.EqStr("root>sub>api", strings.Join(state, ">"))
+	w = serve(router, "GET", "/v1")
+	a.EqStr("api-index", w.Body.String())
+	w = serve(router, "GET", "/v1/unknown")
+	a.EqInt(http.StatusNotFound, w.Code)
+
+	defer func() {
+		x := recover()
+		a.NotNil(x)
+		a.OK(strings.Contains(fmt.Sprint(x), "cannot mount a group of the same router"), x)
+	}()
+	router.Mount("/v2", router.Group("/api"))
+}
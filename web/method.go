@@ -0,0 +1,96 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/yssk22/go/x/xcontext"
+)
+
+// allMethods are the methods registered by All. HEAD and OPTIONS are handled automatically.
+var allMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// routeMethods are the methods in the order to list routes and Allow header values.
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// routing is the router and the path in the router to dispatch the request.
+type routing struct {
+	router *defaultRouter
+	path   string
+}
+
+var routingContextKey = xcontext.NewKey("routing")
+
+// AllowedMethods returns the methods that the router dispatching `req` can handle for the request path.
+// GET routes allow HEAD and any routes allow OPTIONS. `ok` is false if the request is not dispatched by a Router,
+// and the methods are empty if no route matches the path.
+func AllowedMethods(req *Request) (methods []string, ok bool) {
+	r, ok := req.Context().Value(routingContextKey).(*routing)
+	if !ok {
+		return nil, false
+	}
+	return r.router.allowedMethods(r.path), true
+}
+
+// allowedMethods returns the methods that have routes for `path`.
+// The routes mounted by a Router or a group delegate to the mounted routes while
+// the routes mounted by other http.Handler allow every method.
+func (r *defaultRouter) allowedMethods(path string) []string {
+	var methods []string
+	var mounted map[string]bool
+	matched := make(map[string]bool)
+	for method, tree := range r.trees {
+		rt, _ := tree.match(path)
+		if rt == nil {
+			continue
+		}
+		if rt.allowed == nil {
+			matched[method] = true
+			continue
+		}
+		if mounted == nil {
+			mounted = make(map[string]bool)
+			for _, m := range rt.allowed(path) {
+				mounted[m] = true
+			}
+		}
+		if mounted[method] {
+			matched[method] = true
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	if matched["GET"] {
+		matched["HEAD"] = true
+	}
+	matched["OPTIONS"] = true
+	for _, method := range routeMethods {
+		if matched[method] {
+			methods = append(methods, method)
+			delete(matched, method)
+		}
+	}
+	// non standard methods
+	var others []string
+	for method := range matched {
+		others = append(others, method)
+	}
+	sort.Strings(others)
+	return append(methods, others...)
+}
+
+// allowHeader returns the Allow header value
+func allowHeader(methods []string) string {
+	return strings.Join(methods, ", ")
+}
+
+// headResponseWriter is a http.ResponseWriter to suppress the body for HEAD requests.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/yssk22/go/web/response"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestRouter_methods(t *testing.T) {
+	a := assert.New(t)
+	router := NewRouter(nil)
+	router.Get("/users/:id", textHandler("get-"))
+	router.Patch("/users/:id", textHandler("patch-"))
+	router.Delete("/users/:id", textHandler("delete-"))
+	router.Post("/sessions/", textHandler("post"))
+	router.Head("/files/:id", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		res := response.NewText(req.Context(), "")
+		res.Header.Set("X-Head", "explicit")
+		return res
+	}))
+	router.Get("/files/:id", textHandler("file-"))
+	router.Options("/files/:id", textHandler("options-"))
+
+	w := serve(router, "PATCH", "/users/1")
+	a.EqInt(200, w.Code)
+	a.EqStr("patch-1", w.Body.String())
+
+	// automatic HEAD for GET routes
+	w = serve(router, "HEAD", "/users/1")
+	a.EqInt(200, w.Code)
+	a.EqStr("", w.Body.String())
+	a.EqStr("text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// automatic OPTIONS
+	w = serve(router, "OPTIONS", "/users/1")
+	a.EqInt(204, w.Code)
+	a.EqStr("GET, HEAD, PATCH, DELETE, OPTIONS", w.Header().Get("Allow"))
+
+	// method mismatch
+	w = serve(router, "PUT", "/users/1")
+	a.EqInt(405, w.Code)
+	a.EqStr("GET, HEAD, PATCH, DELETE, OPTIONS", w.Header().Get("Allow"))
+	w = serve(router, "GET", "/sessions/")
+	a.EqInt(405, w.Code)
+	a.EqStr("POST, OPTIONS", w.Header().Get("Allow"))
+
+	// explicit HEAD and OPTIONS routes take precedence
+	w = serve(router, "HEAD", "/files/1")
+	a.EqStr("explicit", w.Header().Get("X-Head"))
+	w = serve(router, "OPTIONS", "/files/1")
+	a.EqStr("options-1", w.Body.String())
+
+	w = serve(router, "PUT", "/unknown")
+	a.EqInt(404, w.Code)
+	a.EqStr("", w.Header().Get("Allow"))
+}
+
+func TestRouter_methodsInMount(t *testing.T) {
+	a := assert.New(t)
+	sub := NewRouter(nil)
+	sub.Get("/users/:id", textHandler("get-"))
+	router := NewRouter(nil)
+	router.Mount("/api", sub)
+
+	w := serve(router, "HEAD", "/api/users/1")
+	a.EqInt(200, w.Code)
+	a.EqStr("", w.Body.String())
+
+	w = serve(router, "POST", "/api/users/1")
+	a.EqInt(405, w.Code)
+	a.EqStr("GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestAllowedMethods(t *testing.T) {
+	a := assert.New(t)
+	var allowed []string
+	router := NewRouter(nil)
+	router.Use(HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		allowed, _ = AllowedMethods(req)
+		return next(req)
+	}))
+	router.All("/items/:id", textHandler("item-"))
+
+	serve(router, "GET", "/items/1")
+	a.EqInt(7, len(allowed))
+	serve(router, "GET", "/unknown")
+	a.EqInt(0, len(allowed))
+}
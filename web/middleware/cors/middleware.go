@@ -1,6 +1,8 @@
 package cors
 
 import (
+	"strings"
+
 	"github.com/yssk22/go/web"
 	"github.com/yssk22/go/web/response"
 )
@@ -16,7 +18,12 @@ var defaultPreflight = web.HandlerFunc(func(req *web.Request, next web.NextHandl
 	return resp
 })
 
-// NewMiddleware returns a web.Handler interface for CORS support
+// DefaultAllowMethods is the value of Access-Control-Allow-Methods when the request is not dispatched by web.Router
+const DefaultAllowMethods = "GET,POST,PUT,DELETE,HEAD,OPTIONS"
+
+// NewMiddleware returns a web.Handler interface for CORS support.
+// The preflight response allows the methods that the router has routes for the request path
+// so the middleware should be used by Router.Use. The preflight for the path without routes is not found.
 func NewMiddleware(origins ...string) web.Handler {
 	return NewMiddlewareWithPreflight(defaultPreflight, origins...)
 }
@@ -41,8 +48,16 @@ func NewMiddlewareWithPreflight(preflight web.Handler, origins ...string) web.Ha
 func (m *middleware) Process(req *web.Request, next web.NextHandler) *response.Response {
 	var resp *response.Response
 	if req.Method == "OPTIONS" {
+		allowed := DefaultAllowMethods
+		if methods, ok := web.AllowedMethods(req); ok {
+			if len(methods) == 0 {
+				// no route for the path, the router responds not found.
+				return next(req)
+			}
+			allowed = strings.Join(methods, ",")
+		}
 		resp = m.preflight.Process(req, next)
-		resp.Header.Add("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,HEAD,OPTIONS")
+		resp.Header.Add("Access-Control-Allow-Methods", allowed)
 		resp.Header.Add("Access-Control-Allow-Headers", "Origin,Authorization,Accept,Content-Type")
 	} else {
 		resp = next(req)
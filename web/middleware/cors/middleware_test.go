@@ -0,0 +1,69 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yssk22/go/web"
+	"github.com/yssk22/go/web/response"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestMiddleware_preflight(t *testing.T) {
+	a := assert.New(t)
+	router := web.NewRouter(nil)
+	router.Use(NewMiddleware("https://example.com"))
+	router.Get("/users/:id", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return response.NewText(req.Context(), "OK")
+	}))
+	router.Put("/users/:id", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return response.NewText(req.Context(), "OK")
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/users/1", nil)
+	req.Header.Set("Origin", "https://example.com")
+	router.ServeHTTP(w, req)
+	a.EqInt(200, w.Code)
+	a.EqStr("GET,HEAD,PUT,OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	a.EqStr("https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestMiddleware_preflightNotFound(t *testing.T) {
+	a := assert.New(t)
+	router := web.NewRouter(nil)
+	router.Use(NewMiddleware("*"))
+	router.Get("/users/:id", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return response.NewText(req.Context(), "OK")
+	}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/unknown", nil)
+	router.ServeHTTP(w, req)
+	a.EqInt(404, w.Code)
+	a.EqStr("", w.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestMiddleware_preflightMounted(t *testing.T) {
+	a := assert.New(t)
+	api := web.NewRouter(nil)
+	api.Get("/users/:id", web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return response.NewText(req.Context(), "OK")
+	}))
+	router := web.NewRouter(nil)
+	router.Use(NewMiddleware("*"))
+	router.Mount("/api", api)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("OPTIONS", "/api/users/1", nil)
+	router.ServeHTTP(w, req)
+	a.EqInt(200, w.Code)
+	a.EqStr("GET,HEAD,OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("OPTIONS", "/api/nothing", nil)
+	router.ServeHTTP(w, req)
+	a.EqInt(404, w.Code)
+	a.EqStr("", w.Header().Get("Access-Control-Allow-Methods"))
+}
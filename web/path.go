@@ -111,3 +111,41 @@ func (pattern *PathPattern) Match(path string) (*keyvalue.GetProxy, bool) {
 	}
 	return keyvalue.NewGetProxy(m), true
 }
+
+// Build builds the path by replacing the parameters in the pattern with `params`.
+// The values of :params are double-escaped as Match unescapes them twice, and the values of *params are
+// escaped per segment to keep '/'. It returns an error if a named parameter is missing.
+func (pattern *PathPattern) Build(params map[string]string) (string, error) {
+	var buff strings.Builder
+	for _, t := range tokenize(pattern.source) {
+		if t.typ == tokenStatic {
+			buff.WriteString(t.value)
+			continue
+		}
+		if t.value == "" {
+			// anonymous wildcard
+			continue
+		}
+		v, ok := params[t.value]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter %q", t.value)
+		}
+		if t.typ == tokenParam {
+			if v == "" {
+				return "", fmt.Errorf("empty path parameter %q", t.value)
+			}
+			buff.WriteString(escapeParam(v))
+			continue
+		}
+		segments := strings.Split(v, "/")
+		for i, s := range segments {
+			segments[i] = escapeParam(s)
+		}
+		buff.WriteString(strings.Join(segments, "/"))
+	}
+	return buff.String(), nil
+}
+
+func escapeParam(s string) string {
+	return url.PathEscape(url.QueryEscape(s))
+}
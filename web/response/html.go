@@ -5,21 +5,41 @@ import (
 	"io"
 
 	"context"
+
+	"github.com/yssk22/go/lazy"
 )
 
 type _html struct {
 	template *template.Template
 	data     interface{}
+	prepared bool
+}
+
+// prepare resolves lazy.Value in the data so that errors can be rendered as an error response.
+func (h *_html) prepare(ctx context.Context) error {
+	data, err := lazy.EvalDeep(ctx, h.data)
+	if err != nil {
+		return err
+	}
+	h.data = data
+	h.prepared = true
+	return nil
 }
 
 func (h *_html) Render(ctx context.Context, w io.Writer) {
+	if !h.prepared {
+		if err := h.prepare(ctx); err != nil {
+			panic(err)
+		}
+	}
 	err := h.template.Execute(w, h.data)
 	if err != nil {
 		panic(err)
 	}
 }
 
-// NewHTML returns a new *HTML
+// NewHTML returns a new *HTML.
+// lazy.Value in `data` is evaluated by the response context when rendering.
 func NewHTML(ctx context.Context, template *template.Template, data interface{}) *Response {
 	return NewHTMLWithStatus(ctx, template, data, HTTPStatusOK)
 }
@@ -7,6 +7,7 @@ import (
 
 	"context"
 
+	"github.com/yssk22/go/lazy"
 	"github.com/yssk22/go/x/xtesting/assert"
 )
 
@@ -26,3 +27,18 @@ func TestHTML(t *testing.T) {
 
 	a.EqStr("Sub: This is sub bar", w.Body.String())
 }
+
+func TestHTML_lazy(t *testing.T) {
+	a := assert.New(t)
+
+	var tmpl = template.Must(template.New("foo").Parse("{{.foo}} {{range .list}}{{.}}{{end}}"))
+
+	html := NewHTML(context.Background(), tmpl, map[string]interface{}{
+		"foo":  lazy.New("bar"),
+		"list": []interface{}{lazy.New(1), 2},
+	})
+	w := httptest.NewRecorder()
+	html.Render(w)
+
+	a.EqStr("bar 12", w.Body.String())
+}
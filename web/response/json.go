@@ -1,11 +1,14 @@
 package response
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"reflect"
 
 	"context"
+
+	"github.com/yssk22/go/lazy"
 )
 
 // UseFormattedJSON is a configuration variable about if json object is formatted or not.
@@ -17,38 +20,57 @@ var UseEmptyIfSliceIsZero = true
 var emptyBlacket = []byte("[]\n")
 
 type _json struct {
-	data interface{}
+	data    interface{}
+	content []byte
 }
 
-func (j _json) Render(ctx context.Context, w io.Writer) {
+// prepare resolves lazy.Value in the data and encodes it so that errors can be rendered as an error response.
+func (j *_json) prepare(ctx context.Context) error {
+	data, err := lazy.EvalDeep(ctx, j.data)
+	if err != nil {
+		return err
+	}
 	if UseEmptyIfSliceIsZero {
-		v := reflect.ValueOf(j.data)
+		v := reflect.ValueOf(data)
 		if v.Kind() == reflect.Slice && v.Len() == 0 {
-			w.Write(emptyBlacket)
-			return
+			j.content = emptyBlacket
+			return nil
 		}
 	}
 	if UseFormattedJSON {
-		buff, err := json.MarshalIndent(j.data, "", "    ")
+		buff, err := json.MarshalIndent(data, "", "    ")
 		if err != nil {
-			panic(err)
+			return err
 		}
-		w.Write(buff)
-		return
+		j.content = buff
+		return nil
+	}
+	var buff bytes.Buffer
+	if err := json.NewEncoder(&buff).Encode(data); err != nil {
+		return err
 	}
-	if err := json.NewEncoder(w).Encode(j.data); err != nil {
-		panic(err)
+	j.content = buff.Bytes()
+	return nil
+}
+
+func (j *_json) Render(ctx context.Context, w io.Writer) {
+	if j.content == nil {
+		if err := j.prepare(ctx); err != nil {
+			panic(err)
+		}
 	}
+	w.Write(j.content)
 }
 
-// NewJSON returns a JSON response
+// NewJSON returns a JSON response.
+// lazy.Value in `v` is evaluated by the response context when rendering.
 func NewJSON(ctx context.Context, v interface{}) *Response {
 	return NewJSONWithStatus(ctx, v, HTTPStatusOK)
 }
 
 // NewJSONWithStatus returns a JSON formatted response with the given status code
 func NewJSONWithStatus(ctx context.Context, v interface{}, code HTTPStatus) *Response {
-	res := NewResponseWithStatus(ctx, &_json{v}, code)
+	res := NewResponseWithStatus(ctx, &_json{data: v}, code)
 	res.Header.Set(ContentType, "application/json; charset=utf-8")
 	return res
 }
@@ -1,10 +1,13 @@
 package response
 
 import (
+	"fmt"
 	"net/http/httptest"
 	"testing"
 
 	"context"
+
+	"github.com/yssk22/go/lazy"
 	"github.com/yssk22/go/x/xtesting/assert"
 )
 
@@ -28,3 +31,46 @@ func TestJSON_emptySlice(t *testing.T) {
 
 	a.EqStr("[]\n", w.Body.String())
 }
+
+func TestJSON_lazy(t *testing.T) {
+	a := assert.New(t)
+	json := NewJSON(context.Background(), map[string]interface{}{
+		"ok":    lazy.New(true),
+		"items": []lazy.Value{lazy.New(1), lazy.New(2)},
+	})
+	w := httptest.NewRecorder()
+	json.Render(w)
+
+	a.EqInt(200, w.Code)
+	a.EqStr("{\"items\":[1,2],\"ok\":true}\n", w.Body.String())
+}
+
+func TestJSON_lazyError(t *testing.T) {
+	a := assert.New(t)
+	json := NewJSON(context.Background(), map[string]interface{}{
+		"ok": lazy.Func(func(context.Context) (interface{}, error) {
+			return nil, fmt.Errorf("lazy error")
+		}),
+	})
+	w := httptest.NewRecorder()
+	json.Render(w)
+
+	a.EqInt(500, w.Code)
+	a.EqStr("lazy error", w.Body.String())
+}
+
+func TestJSON_lazyOnce(t *testing.T) {
+	a := assert.New(t)
+	var calls int
+	json := NewJSON(context.Background(), map[string]interface{}{
+		"n": lazy.Func(func(context.Context) (interface{}, error) {
+			calls++
+			return calls, nil
+		}),
+	})
+	a.EqStr("{\"n\":1}\n", json.Content())
+	w := httptest.NewRecorder()
+	json.Render(w)
+	a.EqStr("{\"n\":1}\n", w.Body.String())
+	a.EqInt(1, calls)
+}
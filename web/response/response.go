@@ -6,11 +6,15 @@ import (
 	"net/http"
 
 	"github.com/yssk22/go/x/xcrypto/xhmac"
+	"github.com/yssk22/go/x/xlog"
 	"github.com/yssk22/go/x/xnet/xhttp"
 
 	"context"
 )
 
+// LoggerKey is a key for logger in this package
+const LoggerKey = "web.response"
+
 // Response represents http response.
 type Response struct {
 	Status  HTTPStatus
@@ -18,6 +22,9 @@ type Response struct {
 	Cookies []*http.Cookie
 	Body    Body
 	ctx     context.Context // context when the response is created
+
+	prepared   bool  // true if the body has been prepared
+	prepareErr error // the error on preparing the body
 }
 
 // NewResponse retuurns a *Response to write body content
@@ -47,7 +54,12 @@ func (r *Response) SetCookie(c *http.Cookie, hmac *xhmac.Base64) {
 }
 
 // Render renders whole http contnet
+// If the body fails to prepare its content, such as evaluating lazy values, an error response is rendered instead.
 func (r *Response) Render(w http.ResponseWriter) {
+	if err := r.prepare(); err != nil {
+		NewError(r.ctx, err).Render(w)
+		return
+	}
 	wh := w.Header()
 	for k, v := range r.Header {
 		for _, vv := range v {
@@ -63,6 +75,9 @@ func (r *Response) Render(w http.ResponseWriter) {
 
 // Content returns the rendered result of the response body
 func (r *Response) Content() string {
+	if err := r.prepare(); err != nil {
+		return err.Error()
+	}
 	var buff bytes.Buffer
 	r.Body.Render(context.Background(), &buff)
 	return buff.String()
@@ -78,6 +93,38 @@ type Body interface {
 	Render(ctx context.Context, w io.Writer)
 }
 
+// preparer is an interface for Body to prepare the content before the header is written.
+type preparer interface {
+	prepare(ctx context.Context) error
+}
+
+// prepare prepares the body once so that the lazy values are not evaluated again on each Render or Content.
+func (r *Response) prepare() error {
+	if r.prepared {
+		return r.prepareErr
+	}
+	r.prepared = true
+	r.prepareErr = r.prepareBody()
+	return r.prepareErr
+}
+
+func (r *Response) prepareBody() error {
+	p, ok := r.Body.(preparer)
+	if !ok {
+		return nil
+	}
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := p.prepare(ctx); err != nil {
+		_, logger := xlog.WithContextAndKey(ctx, "response", LoggerKey)
+		logger.Errorf("could not prepare the response body: %v", err)
+		return err
+	}
+	return nil
+}
+
 type noContent struct{}
 
 func (r noContent) Render(ctx context.Context, w io.Writer) {
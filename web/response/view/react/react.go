@@ -35,6 +35,7 @@ type Page struct {
 	javascripts    []string
 	favicon        string
 	config         *PageConfig
+	router         web.Router
 	generator      PageVarsGenerator
 }
 
@@ -113,6 +114,15 @@ func Config(c *PageConfig) PageOption {
 	}
 }
 
+// Routes returns a PageOption to export the named routes of `router` to PageConfig.Routes.
+// The route table is built when the page is rendered so routes can be added after the page is configured.
+func Routes(router web.Router) PageOption {
+	return func(p *Page) (*Page, error) {
+		p.router = router
+		return p, nil
+	}
+}
+
 // Faviconreturns a PageOption to set the favicon path
 func Favicon(favicon string) PageOption {
 	return func(p *Page) (*Page, error) {
@@ -201,6 +211,9 @@ func (p *Page) genVar(req *web.Request) (*PageVars, error) {
 	data.MetaProperties = mergeStringMap(data.MetaProperties, p.metaProperties)
 	data.AppData = mergeObjectMap(data.AppData, p.appData)
 	data.Config = mergeObject(data.Config, p.config).(*PageConfig)
+	if p.router != nil {
+		data.Config = mergeObject(data.Config, &PageConfig{Routes: p.router.RouteTable()}).(*PageConfig)
+	}
 	data.Javascripts = mergeStringList(data.Javascripts, p.javascripts)
 	data.Stylesheets = mergeStringList(data.Stylesheets, p.stylesheets)
 	if p.generator != nil {
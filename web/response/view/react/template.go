@@ -21,6 +21,8 @@ type PageConfig struct {
 	GoogleAnalyticsID string `json:"google_analytics_id"`
 	TwitterID         string `json:"twitter_id"`
 	InstagramID       string `json:"instagram_id"`
+	// Routes is the (name -> path pattern) mapping of the named routes to build paths on the client.
+	Routes map[string]string `json:"routes,omitempty"`
 }
 
 func (c *PageConfig) Merge(obj interface{}) interface{} {
@@ -39,6 +41,12 @@ func (c *PageConfig) Merge(obj interface{}) interface{} {
 	c.GoogleAnalyticsID = mergeString(c.GoogleAnalyticsID, c1.GoogleAnalyticsID)
 	c.TwitterID = mergeString(c.TwitterID, c1.TwitterID)
 	c.InstagramID = mergeString(c.InstagramID, c1.InstagramID)
+	if c1.Routes != nil {
+		if c.Routes == nil {
+			c.Routes = make(map[string]string)
+		}
+		c.Routes = mergeStringMap(c.Routes, c1.Routes)
+	}
 	return c
 }
 
@@ -37,6 +37,7 @@ type Page struct {
 	basePath       string
 	reactAppPath   string
 	config         *PageConfig
+	router         web.Router
 	generator      PageVarsGenerator
 	parent         *Page
 }
@@ -98,6 +99,15 @@ func Config(c *PageConfig) PageOption {
 	}
 }
 
+// Routes returns a PageOption to export the named routes of `router` to PageConfig.Routes.
+// The route table is built when the page is rendered so routes can be added after the page is configured.
+func Routes(router web.Router) PageOption {
+	return func(p *Page) (*Page, error) {
+		p.router = router
+		return p, nil
+	}
+}
+
 // Favicon returns a PageOption to set the favicon path
 func Favicon(favicon string) PageOption {
 	return func(p *Page) (*Page, error) {
@@ -205,6 +215,9 @@ func (p *Page) genVar(req *web.Request) (*PageVars, error) {
 	data.MetaProperties = mergeStringMap(data.MetaProperties, p.metaProperties)
 	data.AppData = mergeObjectMap(data.AppData, p.appData)
 	data.Config = mergeObject(data.Config, p.config).(*PageConfig)
+	if p.router != nil {
+		data.Config = mergeObject(data.Config, &PageConfig{Routes: p.router.RouteTable()}).(*PageConfig)
+	}
 	if p.generator != nil {
 		var genData *PageVars
 		var err error
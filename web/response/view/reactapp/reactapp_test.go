@@ -10,6 +10,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 
 	"github.com/yssk22/go/web"
+	"github.com/yssk22/go/web/response"
 	"github.com/yssk22/go/x/xerrors"
 	"github.com/yssk22/go/x/xtesting/assert"
 )
@@ -100,3 +101,19 @@ func Test_Page_Render_Static(t *testing.T) {
 	a.EqStr("/", appData["url"].(string))
 	a.EqInt(1, doc.Find("script[src='/static/myapp/static/js/main.js']").Length())
 }
+
+func Test_Page_Render_Routes(t *testing.T) {
+	a := assert.New(t)
+	router := web.NewRouter(nil)
+	p, _ := New("myapp", Routes(router))
+	router.Get("/users/:id", web.Named("user.show"), web.HandlerFunc(func(req *web.Request, next web.NextHandler) *response.Response {
+		return p.Render(req)
+	}))
+
+	doc, s := genResponse(p)
+	a.EqInt(200, s.Code)
+	cfg, err := getConfig(doc)
+	a.Nil(err)
+	a.EqInt(1, len(cfg.Routes))
+	a.EqStr("/users/:id", cfg.Routes["user.show"])
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/yssk22/go/keyvalue"
 	"github.com/yssk22/go/web/response"
@@ -19,17 +20,33 @@ type Router interface {
 	Get(string, ...Handler)
 	Post(string, ...Handler)
 	Put(string, ...Handler)
+	Patch(string, ...Handler)
 	Delete(string, ...Handler)
+	Head(string, ...Handler)
+	Options(string, ...Handler)
+	Group(string, ...Handler) Router
+	Mount(string, http.Handler)
+	URL(string, ...interface{}) (string, error)
+	RouteTable() map[string]string
 	ServeHTTP(http.ResponseWriter, *http.Request)
 }
 
 // defaultRouter is a http traffic router default implementation
 type defaultRouter struct {
 	middleware *handlerPipeline
-	routes     map[string][]*route // (method -> []*route) mapping
+	routes     map[string][]*route // (method -> []*route) mapping in the registration order
+	trees      map[string]*node    // (method -> radix tree) mapping to find a route
+	mounts     []*mountedRouter
+	names      map[string]*route // (name -> route) mapping by Named
 	option     *Option
 }
 
+// mountedRouter is a sub router mounted by Mount
+type mountedRouter struct {
+	prefix string
+	router *defaultRouter
+}
+
 // NewRouter returns a new *Router
 func NewRouter(option *Option) Router {
 	if option == nil {
@@ -38,30 +55,41 @@ func NewRouter(option *Option) Router {
 	r := &defaultRouter{
 		middleware: &handlerPipeline{},
 		routes:     make(map[string][]*route),
+		trees:      make(map[string]*node),
+		names:      make(map[string]*route),
 		option:     option,
 	}
 
 	r.Get("/__debug__/routes", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
 		var buff bytes.Buffer
-		r.printRoutes("GET", &buff)
-		r.printRoutes("POST", &buff)
-		r.printRoutes("PUT", &buff)
-		r.printRoutes("DELETE", &buff)
+		for _, method := range routeMethods {
+			r.printRoutes(method, &buff)
+		}
 		return response.NewText(req.Context(), buff.String())
 	}))
 	return r
 }
 
 func (r *defaultRouter) printRoutes(method string, dst io.Writer) {
+	r.printRoutesWithPrefix(method, "", dst)
+}
+
+func (r *defaultRouter) printRoutesWithPrefix(method string, prefix string, dst io.Writer) {
 	if routes, ok := r.routes[method]; ok {
 		for _, r := range routes {
+			if r.mount {
+				continue
+			}
 			if _, err := dst.Write(
-				[]byte(fmt.Sprintf("%s %s\n", r.method, r.pattern.source)),
+				[]byte(fmt.Sprintf("%s %s\n", r.method, joinPath(prefix, r.pattern.source))),
 			); err != nil {
 				panic(err)
 			}
 		}
 	}
+	for _, m := range r.mounts {
+		m.router.printRoutesWithPrefix(method, joinPath(prefix, m.prefix), dst)
+	}
 }
 
 // Use adds middleware handlers to process on every request before all handlers are processed.
@@ -69,12 +97,11 @@ func (r *defaultRouter) Use(handlers ...Handler) {
 	r.middleware.Append(handlers...)
 }
 
-// All adds handlers for "GET|PUT|POST|DELETE {pattern}" requests
+// All adds handlers for "GET|POST|PUT|PATCH|DELETE {pattern}" requests
 func (r *defaultRouter) All(pattern string, handlers ...Handler) {
-	r.addRoute("GET", pattern, handlers...)
-	r.addRoute("PUT", pattern, handlers...)
-	r.addRoute("POST", pattern, handlers...)
-	r.addRoute("DELETE", pattern, handlers...)
+	for _, method := range allMethods {
+		r.addRoute(method, pattern, handlers...)
+	}
 }
 
 // Get adds handlers for "GET {pattern}" requests
@@ -92,53 +119,202 @@ func (r *defaultRouter) Put(pattern string, handlers ...Handler) {
 	r.addRoute("PUT", pattern, handlers...)
 }
 
+// Patch adds handlers for "PATCH {pattern}" requests
+func (r *defaultRouter) Patch(pattern string, handlers ...Handler) {
+	r.addRoute("PATCH", pattern, handlers...)
+}
+
 // Delete adds handlers for "DELETE {pattern}" requests
 func (r *defaultRouter) Delete(pattern string, handlers ...Handler) {
 	r.addRoute("DELETE", pattern, handlers...)
 }
 
-func (r *defaultRouter) addRoute(method string, pattern string, handlers ...Handler) {
+// Head adds handlers for "HEAD {pattern}" requests.
+// Without this, HEAD requests are handled by GET routes and the body is suppressed.
+func (r *defaultRouter) Head(pattern string, handlers ...Handler) {
+	r.addRoute("HEAD", pattern, handlers...)
+}
+
+// Options adds handlers for "OPTIONS {pattern}" requests.
+// Without this, OPTIONS requests are responded with the Allow header.
+func (r *defaultRouter) Options(pattern string, handlers ...Handler) {
+	r.addRoute("OPTIONS", pattern, handlers...)
+}
+
+// Group returns a Router to add routes under `prefix`. `middleware` and the handlers added by Use of the group
+// are processed only for the routes in the group, after the middleware of the parent.
+func (r *defaultRouter) Group(prefix string, middleware ...Handler) Router {
+	return newGroup(r, nil, prefix, middleware...)
+}
+
+// Mount dispatches the requests under `prefix` to `h`. If `h` is a Router created by NewRouter, its routes
+// are dispatched with the current request after its middleware, and listed in the debug routes with the prefix.
+// If `h` is a Router returned by Group of another router, the group routes are dispatched in the same way.
+// Otherwise, `h` is served with the path without the prefix.
+func (r *defaultRouter) Mount(prefix string, h http.Handler) {
+	r.mount(prefix, h)
+}
+
+func (r *defaultRouter) mount(prefix string, h http.Handler, handlers ...Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	var handler Handler
+	if g, ok := h.(*group); ok {
+		if g.root == r {
+			panic(fmt.Errorf("web: cannot mount a group of the same router on %s", prefix))
+		}
+		// the routes of the group are stored in its root with the group prefix.
+		handler = HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+			path := joinPath(g.prefix, mountedPath(req.URL.EscapedPath(), prefix))
+			return response.NewResponse(req.Context(), &mountedHandler{
+				handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					g.root.dispatch(w, req, path)
+				}),
+				request: req.Request,
+			})
+		})
+		allowed := func(path string) []string {
+			return g.root.allowedMethods(joinPath(g.prefix, mountedPath(path, prefix)))
+		}
+		r.addMountRoutes(prefix, false, allowed, append(handlers, handler)...)
+		return
+	}
+	var allowed func(string) []string
+	sub, isRouter := h.(*defaultRouter)
+	if isRouter {
+		r.mounts = append(r.mounts, &mountedRouter{
+			prefix: prefix,
+			router: sub,
+		})
+		handler = HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+			path := mountedPath(req.URL.EscapedPath(), prefix)
+			return response.NewResponse(req.Context(), &mountedHandler{
+				handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					sub.dispatch(w, req, path)
+				}),
+				request: req.Request,
+			})
+		})
+		allowed = func(path string) []string {
+			return sub.allowedMethods(mountedPath(path, prefix))
+		}
+	} else {
+		handler = HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+			return response.NewResponse(req.Context(), &mountedHandler{
+				handler: http.StripPrefix(prefix, h),
+				request: req.Request,
+			})
+		})
+	}
+	r.addMountRoutes(prefix, isRouter, allowed, append(handlers, handler)...)
+}
+
+// addMountRoutes adds the routes to dispatch the requests under `prefix`.
+// All methods are dispatched so that the mounted router handles HEAD, OPTIONS and 405 by itself.
+// `allowed` returns the methods that the mounted router allows for the path, or nil if `h` is not a Router.
+func (r *defaultRouter) addMountRoutes(prefix string, isRouter bool, allowed func(string) []string, handlers ...Handler) {
+	for _, method := range routeMethods {
+		for _, pattern := range []string{prefix, prefix + "/*"} {
+			if pattern == "" {
+				continue
+			}
+			// the mounted router lists its own routes in the debug routes.
+			rt := r.addRoute(method, pattern, handlers...)
+			rt.mount = isRouter
+			rt.allowed = allowed
+		}
+	}
+}
+
+// mountedPath returns the path for the mounted router
+func mountedPath(path string, prefix string) string {
+	path = strings.TrimPrefix(path, prefix)
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// joinPath joins the prefix and the pattern
+func joinPath(prefix string, pattern string) string {
+	return strings.TrimSuffix(prefix, "/") + pattern
+}
+
+// addRoute adds the handlers for the route. If the pattern already exists, the handlers are appended to the route.
+// It panics if the pattern is ambiguous with the existing one, e.g. "/users/:id" and "/users/:name".
+func (r *defaultRouter) addRoute(method string, pattern string, handlers ...Handler) *route {
 	var rt *route
-	if routes, ok := r.routes[method]; !ok {
+	for _, _rt := range r.routes[method] {
+		if _rt.pattern.source == pattern {
+			rt = _rt
+			break
+		}
+	}
+	if rt == nil {
 		rt = newRoute(method, pattern)
-		r.routes[method] = []*route{rt}
-	} else {
-		// if the pattern already exists in current routes, the new handlers should be merged.
-		for _, _rt := range routes {
-			if _rt.pattern.source == pattern {
-				rt = _rt
-				break
+		normalized := normalizePattern(rt.tokens)
+		for _, _rt := range r.routes[method] {
+			if normalizePattern(_rt.tokens) == normalized {
+				panic(fmt.Errorf("web: ambiguous route %s %s conflicts with %s", method, pattern, _rt.pattern.source))
 			}
 		}
-		if rt == nil {
-			rt = newRoute(method, pattern)
-			r.routes[method] = append(routes, rt)
+		tree, ok := r.trees[method]
+		if !ok {
+			tree = &node{}
+			r.trees[method] = tree
 		}
+		if err := tree.add(rt.tokens, rt); err != nil {
+			panic(fmt.Errorf("web: ambiguous route %v", err))
+		}
+		r.routes[method] = append(r.routes[method], rt)
 	}
-	rt.pipeline.Append(handlers...)
+	rt.pipeline.Append(r.nameRoute(rt, handlers)...)
+	return rt
 }
 
 // Dispatch dispaches *http.Request to the matched handlers and return Response
 func (r *defaultRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	const RequestIDHeader = "X-SPEEDLAND-REQUEST-ID"
 	var request = NewRequest(req, r.option)
-	var logger = xlog.WithKey("web.router").WithContext(request.Context())
 	w.Header().Set(RequestIDHeader, request.ID.String())
+	r.dispatch(w, request, req.URL.EscapedPath())
+}
 
+// dispatch processes the middleware and renders the response of the route for `path`.
+func (r *defaultRouter) dispatch(w http.ResponseWriter, request *Request, path string) {
+	var logger = xlog.WithKey("web.router").WithContext(request.Context())
+	request = request.WithValue(routingContextKey, &routing{router: r, path: path})
+	if request.Method == "HEAD" {
+		w = &headResponseWriter{w}
+	}
+	var rendered bool
 	// middleware always executed
-	r.middleware.Process(
+	res := r.middleware.Process(
 		request,
 		NextHandler(func(request *Request) *response.Response {
+			rendered = true
 			// then find a route to dispatch
-			path := req.URL.EscapedPath()
-			method := req.Method
-			matched := r.findRoute(method, path)
-			// bind common fields with request
-			if matched == nil {
+			method := request.Method
+			route, pathParams := r.findRoute(method, path)
+			if route == nil && method == "HEAD" {
+				route, pathParams = r.findRoute("GET", path)
+			}
+			if route == nil {
+				allowed := r.allowedMethods(path)
+				if len(allowed) > 0 {
+					var res *response.Response
+					if method == "OPTIONS" {
+						res = response.NewResponseWithStatus(request.Context(), response.NoContent, response.HTTPStatusNoContent)
+					} else {
+						res = response.NewTextWithStatus(request.Context(), "method not allowed", response.HTTPStatusMethodNotAllowed)
+					}
+					res.Header.Set("Allow", allowHeader(allowed))
+					r.renderResponse(request.Context(), w, res)
+					return nil
+				}
 				// Debugging for the route is collectly configured or not.
 				logger.Debug(func(p *xlog.Printer) {
 					p.Printf("No route is found for \"%s %s\":\n", method, path)
-					for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+					for _, method := range routeMethods {
 						for _, r := range r.routes[method] {
 							p.Printf("\t%s %s\n", r.method, r.pattern.source)
 						}
@@ -147,44 +323,22 @@ func (r *defaultRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				r.renderResponse(request.Context(), w, response.NewTextWithStatus(request.Context(), "not found", response.HTTPStatusNotFound))
 				return nil
 			}
-			if len(matched) == 1 {
-				route := matched[0].Route
-				pathParams := matched[0].Params
-				logger.Debug(func(p *xlog.Printer) {
-					p.Printf("Routing matched: %s => %s", req.URL.Path, route.pattern.source)
-					for _, name := range route.pattern.paramNames {
-						p.Printf("\n\t%s=%s", name, pathParams.GetStringOr(name, ""))
-					}
-				})
-				request.Params = pathParams
-				res := route.pipeline.Process(request.WithValue(requestContextKey, request), nil)
-				r.renderResponse(req.Context(), w, res)
-				return nil
-			}
-			// dynamic creation of pipeline
-			var handlers []Handler
-			for _, m := range matched {
-				route := m.Route
-				pathParams := m.Params
-				logger.Debug(func(p *xlog.Printer) {
-					p.Printf("Routing: %s => %s\n", req.URL.Path, route.pattern.source)
-					for _, name := range route.pattern.paramNames {
-						p.Printf("\t%s=%s\n", name, pathParams.GetStringOr(name, ""))
-					}
-				})
-				handlers = append(handlers, HandlerFunc(func(req *Request, next NextHandler) *response.Response {
-					req.Params = pathParams
-					return next(req)
-				}))
-				handlers = append(handlers, m.Route.pipeline.Handlers...)
-			}
-			var dynamic = &handlerPipeline{}
-			dynamic.Append(handlers...)
-			res := dynamic.Process(request.WithValue(requestContextKey, request), nil)
-			r.renderResponse(req.Context(), w, res)
+			logger.Debug(func(p *xlog.Printer) {
+				p.Printf("Routing matched: %s => %s", path, route.pattern.source)
+				for _, name := range route.pattern.paramNames {
+					p.Printf("\n\t%s=%s", name, pathParams.GetStringOr(name, ""))
+				}
+			})
+			request.Params = pathParams
+			res := route.pipeline.Process(request.WithValue(requestContextKey, request), nil)
+			r.renderResponse(request.Context(), w, res)
 			return nil
 		}),
 	)
+	// the middleware responds without routing (e.g. CORS preflight)
+	if !rendered && res != nil {
+		r.renderResponse(request.Context(), w, res)
+	}
 }
 
 func (r *defaultRouter) renderResponse(ctx context.Context, w http.ResponseWriter, res *response.Response) {
@@ -192,44 +346,61 @@ func (r *defaultRouter) renderResponse(ctx context.Context, w http.ResponseWrite
 		response.NewTextWithStatus(ctx, "not found", response.HTTPStatusNotFound).Render(w)
 		return
 	}
+	if m, ok := res.Body.(*mountedHandler); ok {
+		// the mounted handler writes the status by itself.
+		wh := w.Header()
+		for k, v := range res.Header {
+			for _, vv := range v {
+				wh.Add(k, vv)
+			}
+		}
+		for _, c := range res.Cookies {
+			http.SetCookie(w, c)
+		}
+		m.handler.ServeHTTP(w, m.request)
+		return
+	}
 	res.Render(w)
 }
 
-type matchedRoute struct {
-	Route  *route
-	Params *keyvalue.GetProxy
+// mountedHandler is a response.Body to serve the request by the mounted handler.
+type mountedHandler struct {
+	handler http.Handler
+	request *http.Request
 }
 
-func (r *defaultRouter) findRoute(method string, path string) []*matchedRoute {
-	if methodRoutes, ok := r.routes[method]; ok {
-		var matched []*matchedRoute
-		var found = false
-		for _, route := range methodRoutes {
-			if params, ok := route.pattern.Match(path); ok {
-				matched = append(matched, &matchedRoute{
-					route,
-					params,
-				})
-				found = true
-			}
-		}
-		if found {
-			return matched
-		}
+func (m *mountedHandler) Render(ctx context.Context, w io.Writer) {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		m.handler.ServeHTTP(rw, m.request)
+	}
+}
+
+// findRoute finds the route for the escaped path by the radix tree. When multiple patterns match the path,
+// static segments take precedence over :params, and :params over *wildcards.
+func (r *defaultRouter) findRoute(method string, path string) (*route, *keyvalue.GetProxy) {
+	tree, ok := r.trees[method]
+	if !ok {
+		return nil, nil
 	}
-	return nil
+	return tree.match(path)
 }
 
 type route struct {
 	method   string
 	pattern  *PathPattern
+	tokens   []token
 	pipeline *handlerPipeline
+	name     string                     // the name by Named
+	mount    bool                       // true if the route dispatches to a Router mounted by Mount
+	allowed  func(path string) []string // the methods allowed by the mounted Router or group for the path
 }
 
 func newRoute(method, pattern string) *route {
+	compiled := MustCompilePathPattern(pattern)
 	return &route{
 		method:   method,
-		pattern:  MustCompilePathPattern(pattern),
+		pattern:  compiled,
+		tokens:   tokenize(compiled.source),
 		pipeline: &handlerPipeline{},
 	}
 }
@@ -110,44 +110,56 @@ func ExampleRouter_Use() {
 func ExampleRouter_multipleRoute() {
 	router := NewRouter(nil)
 	router.Get("/:key.html", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
-		return next(req.WithValue(
-			"my-middleware-key",
-			req.Params.GetStringOr("key", "default"),
-		))
+		return response.NewText(req.Context(), fmt.Sprintf("param-%s", req.Params.GetStringOr("key", "")))
 	}))
 	router.Get("/a.html",
 		HandlerFunc(func(req *Request, next NextHandler) *response.Response {
-			v, _ := req.Get("my-middleware-key")
-			return response.NewText(req.Context(), fmt.Sprintf("a-%s", v))
+			return response.NewText(req.Context(), "static-a")
 		}),
 	)
-	router.Get("/b.html",
+	router.Get("/*path",
 		HandlerFunc(func(req *Request, next NextHandler) *response.Response {
-			v, _ := req.Get("my-middleware-key")
-			return response.NewText(req.Context(), fmt.Sprintf("b-%s", v))
+			return response.NewText(req.Context(), fmt.Sprintf("wildcard-%s", req.Params.GetStringOr("path", "")))
 		}),
 	)
 
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/a.html", nil)
-	router.ServeHTTP(w, req)
-	fmt.Printf("*response.Response: %q\n", w.Body)
-
-	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("GET", "/b.html", nil)
-	router.ServeHTTP(w, req)
-	fmt.Printf("*response.Response: %q\n", w.Body)
-
-	// not found route even /:key.html handles some
-	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("GET", "/c.html", nil)
-	router.ServeHTTP(w, req)
-	fmt.Printf("*response.Response: %q\n", w.Body)
+	// static > param > wildcard
+	for _, path := range []string{"/a.html", "/b.html", "/c.txt", "/path/to/d.html"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		fmt.Printf("*response.Response: %q\n", w.Body)
+	}
 
 	// Output:
-	// *response.Response: "a-a"
-	// *response.Response: "b-b"
-	// *response.Response: "not found"
+	// *response.Response: "static-a"
+	// *response.Response: "param-b"
+	// *response.Response: "wildcard-c.txt"
+	// *response.Response: "wildcard-path/to/d.html"
+}
+
+func TestRouter_ambiguousRoute(t *testing.T) {
+	a := assert.New(t)
+	router := NewRouter(nil)
+	router.Get("/users/:id", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return nil
+	}))
+	// the same pattern is merged
+	router.Get("/users/:id", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return nil
+	}))
+	// other methods are independent
+	router.Post("/users/:name", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return nil
+	}))
+	defer func() {
+		x := recover()
+		a.NotNil(x)
+		a.OK(strings.Contains(fmt.Sprint(x), "/users/:name conflicts with /users/:id"), x)
+	}()
+	router.Get("/users/:name", HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return nil
+	}))
 }
 
 func TestRouter_middlewareBeforeAfter(t *testing.T) {
@@ -0,0 +1,278 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/yssk22/go/keyvalue"
+)
+
+type tokenType int
+
+const (
+	tokenStatic tokenType = iota
+	tokenParam
+	tokenWildcard
+)
+
+// token is a part of the path pattern
+type token struct {
+	typ   tokenType
+	value string // static string or parameter name
+}
+
+// tokenize splits the path pattern source into the static strings, :params and *wildcards.
+func tokenize(source string) []token {
+	var tokens []token
+	var static strings.Builder
+	flush := func() {
+		if static.Len() > 0 {
+			tokens = append(tokens, token{tokenStatic, static.String()})
+			static.Reset()
+		}
+	}
+	for i := 0; i < len(source); {
+		c := source[i]
+		if c != ':' && c != '*' {
+			static.WriteByte(c)
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(source) && isParamNameChar(source[j]) {
+			j++
+		}
+		if c == ':' && j == i+1 {
+			// ':' without a name is a static character
+			static.WriteByte(c)
+			i++
+			continue
+		}
+		flush()
+		if c == ':' {
+			tokens = append(tokens, token{tokenParam, source[i+1 : j]})
+		} else {
+			tokens = append(tokens, token{tokenWildcard, source[i+1 : j]})
+		}
+		i = j
+	}
+	flush()
+	return tokens
+}
+
+func isParamNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// normalizePattern returns the pattern without parameter names to detect ambiguous patterns.
+func normalizePattern(tokens []token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		switch t.typ {
+		case tokenStatic:
+			b.WriteString(t.value)
+		case tokenParam:
+			b.WriteString(":")
+		case tokenWildcard:
+			b.WriteString("*")
+		}
+	}
+	return b.String()
+}
+
+// node is a node of the radix tree for routes. The static children share the common prefixes
+// and the matching tries static children first, then params, then wildcards.
+type node struct {
+	typ       tokenType
+	prefix    string // for static nodes
+	name      string // for param and wildcard nodes
+	children  []*node
+	params    []*node
+	wildcards []*node
+	route     *route
+}
+
+// add adds the route to the tree. It returns an error if an ambiguous route is already registered.
+func (n *node) add(tokens []token, rt *route) error {
+	cur := n
+	for _, t := range tokens {
+		switch t.typ {
+		case tokenStatic:
+			cur = cur.addStatic(t.value)
+		case tokenParam:
+			cur = cur.addDynamic(&cur.params, tokenParam, t.value)
+		case tokenWildcard:
+			cur = cur.addDynamic(&cur.wildcards, tokenWildcard, t.value)
+		}
+	}
+	if cur.route != nil && cur.route != rt {
+		return fmt.Errorf("%s %s conflicts with %s", rt.method, rt.pattern.source, cur.route.pattern.source)
+	}
+	cur.route = rt
+	return nil
+}
+
+func (n *node) addStatic(s string) *node {
+	for i, child := range n.children {
+		l := commonPrefixLength(child.prefix, s)
+		if l == 0 {
+			continue
+		}
+		if l < len(child.prefix) {
+			// split the child by the common prefix
+			split := &node{
+				typ:      tokenStatic,
+				prefix:   child.prefix[:l],
+				children: []*node{child},
+			}
+			child.prefix = child.prefix[l:]
+			n.children[i] = split
+			child = split
+		}
+		if l == len(s) {
+			return child
+		}
+		return child.addStatic(s[l:])
+	}
+	child := &node{
+		typ:    tokenStatic,
+		prefix: s,
+	}
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *node) addDynamic(list *[]*node, typ tokenType, name string) *node {
+	for _, child := range *list {
+		if child.name == name {
+			return child
+		}
+	}
+	child := &node{
+		typ:  typ,
+		name: name,
+	}
+	*list = append(*list, child)
+	return child
+}
+
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// pathParam is a captured parameter value
+type pathParam struct {
+	name  string
+	value string
+}
+
+// match finds the route for the (escaped) `path` and returns it with the parameters.
+func (n *node) match(path string) (*route, *keyvalue.GetProxy) {
+	var params []pathParam
+	rt := n.find(path, &params)
+	if rt == nil {
+		return nil, nil
+	}
+	m := keyvalue.NewMap()
+	for _, p := range params {
+		if p.name != "" {
+			m[p.name] = p.value
+		}
+	}
+	return rt, keyvalue.NewGetProxy(m)
+}
+
+// find finds the route for the path after the node. The priority is static > param > wildcard
+// and it backtracks to the lower priority children if the higher ones do not match the rest of the path.
+func (n *node) find(path string, params *[]pathParam) *route {
+	if path == "" && n.route != nil {
+		return n.route
+	}
+	for _, child := range n.children {
+		if strings.HasPrefix(path, child.prefix) {
+			if rt := child.find(path[len(child.prefix):], params); rt != nil {
+				return rt
+			}
+		}
+	}
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		for _, child := range n.params {
+			// the longest value first as the regexp does, e.g. /:name.html for /a.b.html
+			if rt := child.findDynamic(path, end, 1, params); rt != nil {
+				return rt
+			}
+		}
+	}
+	for _, child := range n.wildcards {
+		if rt := child.findDynamic(path, len(path), 0, params); rt != nil {
+			return rt
+		}
+	}
+	return nil
+}
+
+// findDynamic tries the values path[:max] to path[:min] for the param or wildcard node.
+func (n *node) findDynamic(path string, max int, min int, params *[]pathParam) *route {
+	if len(n.children) == 0 && len(n.params) == 0 && len(n.wildcards) == 0 {
+		// only the whole rest of the path can match the leaf
+		if max < len(path) {
+			return nil
+		}
+		min = max
+	} else if n.typ == tokenParam && n.endsWithSegment() {
+		min = max
+	}
+	size := len(*params)
+	for i := max; i >= min; i-- {
+		value, ok := unescapeParam(path[:i])
+		if !ok {
+			continue
+		}
+		*params = append((*params)[:size], pathParam{n.name, value})
+		if rt := n.find(path[i:], params); rt != nil {
+			return rt
+		}
+	}
+	*params = (*params)[:size]
+	return nil
+}
+
+// endsWithSegment returns true if the param is always followed by '/' or the end of the path
+// so that the param value is the whole segment.
+func (n *node) endsWithSegment() bool {
+	if len(n.params) > 0 || len(n.wildcards) > 0 {
+		return false
+	}
+	for _, child := range n.children {
+		if child.prefix[0] != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeParam unescapes the value twice.
+//
+// GAE server pass url encoded values to programs and clients should pass double-encoded values
+// For example, the client should path /path%252Fto%252Ffoo.json
+// if they want handle /path/to/foo.json as /:param.json (set param = "path/to/foo"),
+func unescapeParam(s string) (string, bool) {
+	v, err := url.QueryUnescape(s)
+	if err != nil {
+		return "", false
+	}
+	v, err = url.QueryUnescape(v)
+	if err != nil {
+		return "", false
+	}
+	return v, true
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func newTestTree(patterns ...string) *node {
+	tree := &node{}
+	for _, p := range patterns {
+		if err := tree.add(tokenize(p), newRoute("GET", p)); err != nil {
+			panic(err)
+		}
+	}
+	return tree
+}
+
+func TestTokenize(t *testing.T) {
+	a := assert.New(t)
+	var tokens []string
+	for _, t := range tokenize("/path/:id.html/*rest/a:/*") {
+		tokens = append(tokens, fmt.Sprintf("%d%s", t.typ, t.value))
+	}
+	a.EqStr("0/path/,1id,0.html/,2rest,0/a:/,2", strings.Join(tokens, ","))
+	a.EqStr("/path/:.html/*/a:/*", normalizePattern(tokenize("/path/:id.html/*rest/a:/*")))
+}
+
+func TestNode_match(t *testing.T) {
+	a := assert.New(t)
+	tree := newTestTree(
+		"/",
+		"/users/",
+		"/users/me",
+		"/users/:id",
+		"/users/:id/edit",
+		"/users/:id/*rest",
+		"/files/:name.html",
+		"/files/:name.json",
+		"/static/*",
+		"/:category/items",
+	)
+	for path, expected := range map[string]string{
+		"/":                     "/ ",
+		"/users/":               "/users/ ",
+		"/users/me":             "/users/me ",
+		"/users/mee":            "/users/:id id=mee",
+		"/users/123":            "/users/:id id=123",
+		"/users/123/edit":       "/users/:id/edit id=123",
+		"/users/123/edit/more":  "/users/:id/*rest id=123 rest=edit/more",
+		"/users/me/edit":        "/users/:id/edit id=me",
+		"/files/a.b.html":       "/files/:name.html name=a.b",
+		"/files/a.json":         "/files/:name.json name=a",
+		"/files/a%252Fb.json":   "/files/:name.json name=a/b",
+		"/static/js/app.js":     "/static/* ",
+		"/static/":              "/static/* ",
+		"/books/items":          "/:category/items category=books",
+		"/users/items":          "/users/:id id=items",
+		"/files/a.txt":          "",
+		"/users":                "",
+		"/books/items/too/long": "",
+	} {
+		rt, params := tree.match(path)
+		if expected == "" {
+			a.Nil(rt, path)
+			continue
+		}
+		a.NotNil(rt, path)
+		if rt == nil {
+			continue
+		}
+		actual := []string{rt.pattern.source}
+		for _, name := range rt.pattern.GetParamNames() {
+			actual = append(actual, fmt.Sprintf("%s=%s", name, params.GetStringOr(name, "")))
+		}
+		if len(actual) == 1 {
+			actual = append(actual, "")
+		}
+		a.EqStr(expected, strings.Join(actual, " "), path)
+	}
+}
+
+// the patterns from a typical application
+var benchmarkPatterns = []string{
+	"/",
+	"/about.html",
+	"/api/users/",
+	"/api/users/:id",
+	"/api/users/:id/posts/",
+	"/api/users/:id/posts/:post_id",
+	"/api/users/:id/posts/:post_id/comments/",
+	"/api/posts/",
+	"/api/posts/:id",
+	"/api/posts/:id/comments/",
+	"/api/tags/:tag.json",
+	"/admin/",
+	"/admin/users/:id/edit",
+	"/admin/settings/",
+	"/assets/*path",
+	"/auth/login",
+	"/auth/logout",
+	"/auth/callback/:provider",
+	"/:lang/pages/:page.html",
+	"/__debug__/routes",
+}
+
+var benchmarkPaths = []string{
+	"/",
+	"/api/users/123/posts/456/comments/",
+	"/api/tags/golang.json",
+	"/assets/js/vendor/app.min.js",
+	"/ja/pages/index.html",
+	"/not/found",
+}
+
+// BenchmarkRouter_tree is the radix tree lookup used by defaultRouter.
+func BenchmarkRouter_tree(b *testing.B) {
+	tree := newTestTree(benchmarkPatterns...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchmarkPaths {
+			tree.match(path)
+		}
+	}
+}
+
+// BenchmarkRouter_linear is the previous implementation that scans all compiled patterns.
+func BenchmarkRouter_linear(b *testing.B) {
+	var patterns []*PathPattern
+	for _, p := range benchmarkPatterns {
+		patterns = append(patterns, MustCompilePathPattern(p))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range benchmarkPaths {
+			for _, p := range patterns {
+				p.Match(path)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/yssk22/go/web/response"
+)
+
+// namedHandler is a Handler to name the route.
+type namedHandler struct {
+	name string
+}
+
+func (h *namedHandler) Process(req *Request, next NextHandler) *response.Response {
+	return next(req)
+}
+
+// Named returns a Handler to name the route so that URL can build the path of the route.
+//
+//    router.Get("/users/:id", web.Named("user.show"), handler)
+//    router.URL("user.show", "id", 1) // => "/users/1"
+//
+func Named(name string) Handler {
+	return &namedHandler{name: name}
+}
+
+// nameRoute sets the name of `rt` by Named handlers in `handlers` and returns the rest of handlers.
+func (r *defaultRouter) nameRoute(rt *route, handlers []Handler) []Handler {
+	var rest []Handler
+	for _, h := range handlers {
+		named, ok := h.(*namedHandler)
+		if !ok {
+			rest = append(rest, h)
+			continue
+		}
+		if other, ok := r.names[named.name]; ok && other.pattern.source != rt.pattern.source {
+			panic(fmt.Errorf("web: route name %q for %s is already used for %s", named.name, rt.pattern.source, other.pattern.source))
+		}
+		rt.name = named.name
+		r.names[named.name] = rt
+	}
+	return rest
+}
+
+// URL returns the path of the route named `name` with the path parameters.
+// `params` are pairs of a parameter name and its value, such as ("id", 1), and the values are escaped.
+// Routes in the routers mounted by Mount can also be built with the mount prefix.
+func (r *defaultRouter) URL(name string, params ...interface{}) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("web: odd number of params for the route %q", name)
+	}
+	m := make(map[string]string)
+	for i := 0; i < len(params); i += 2 {
+		m[fmt.Sprint(params[i])] = fmt.Sprint(params[i+1])
+	}
+	pattern, ok := r.findNamedPattern(name)
+	if !ok {
+		return "", fmt.Errorf("web: no route is named %q", name)
+	}
+	path, err := MustCompilePathPattern(pattern).Build(m)
+	if err != nil {
+		return "", fmt.Errorf("web: could not build the route %q: %v", name, err)
+	}
+	return path, nil
+}
+
+func (r *defaultRouter) findNamedPattern(name string) (string, bool) {
+	if rt, ok := r.names[name]; ok {
+		return rt.pattern.source, true
+	}
+	for _, m := range r.mounts {
+		if pattern, ok := m.router.findNamedPattern(name); ok {
+			return joinPath(m.prefix, pattern), true
+		}
+	}
+	return "", false
+}
+
+// RouteTable returns the (name -> path pattern) mapping of the named routes, including the routes in the routers
+// mounted by Mount, to share the routes with clients.
+func (r *defaultRouter) RouteTable() map[string]string {
+	table := make(map[string]string)
+	r.collectRouteTable("", table)
+	return table
+}
+
+func (r *defaultRouter) collectRouteTable(prefix string, table map[string]string) {
+	for _, m := range r.mounts {
+		m.router.collectRouteTable(joinPath(prefix, m.prefix), table)
+	}
+	// the names in the router take precedence over the mounted ones as URL does.
+	for name, rt := range r.names {
+		table[name] = joinPath(prefix, rt.pattern.source)
+	}
+}
+
+// TemplateFuncs returns template functions to build paths by `router`.
+//
+//    - url: {{url "user.show" "id" .ID}} is the same as router.URL("user.show", "id", .ID)
+//
+func TemplateFuncs(router Router) template.FuncMap {
+	return template.FuncMap(map[string]interface{}{
+		"url": router.URL,
+	})
+}
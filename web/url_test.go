@@ -0,0 +1,81 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/yssk22/go/web/response"
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestRouter_URL(t *testing.T) {
+	a := assert.New(t)
+	router := NewRouter(nil)
+	router.Get("/users/:id", Named("user.show"), textHandler("user-"))
+	router.Get("/files/*path", Named("file.show"), HandlerFunc(func(req *Request, next NextHandler) *response.Response {
+		return response.NewText(req.Context(), req.Params.GetStringOr("path", ""))
+	}))
+	api := router.Group("/api")
+	api.All("/items/:id", Named("api.item"), textHandler("item-"))
+	sub := NewRouter(nil)
+	sub.Get("/posts/:id", Named("blog.post"), textHandler("post-"))
+	router.Mount("/blog", sub)
+
+	path, err := router.URL("user.show", "id", 1)
+	a.Nil(err)
+	a.EqStr("/users/1", path)
+	a.EqStr("user-1", serve(router, "GET", path).Body.String())
+
+	// escaped values are unescaped by the router
+	path, err = router.URL("user.show", "id", "a/b c+d")
+	a.Nil(err)
+	a.EqStr("user-a/b c+d", serve(router, "GET", path).Body.String())
+	path, err = router.URL("file.show", "path", "to/my file.txt")
+	a.Nil(err)
+	a.EqStr("/files/to/my+file.txt", path)
+	a.EqStr("to/my file.txt", serve(router, "GET", path).Body.String())
+
+	path, err = api.URL("api.item", "id", "x")
+	a.Nil(err)
+	a.EqStr("/api/items/x", path)
+	path, err = router.URL("blog.post", "id", 2)
+	a.Nil(err)
+	a.EqStr("/blog/posts/2", path)
+	a.EqStr("post-2", serve(router, "GET", path).Body.String())
+
+	_, err = router.URL("user.show")
+	a.NotNil(err)
+	_, err = router.URL("user.show", "id")
+	a.NotNil(err)
+	_, err = router.URL("unknown")
+	a.NotNil(err)
+
+	table := router.RouteTable()
+	a.EqInt(4, len(table))
+	a.EqStr("/users/:id", table["user.show"])
+	a.EqStr("/blog/posts/:id", table["blog.post"])
+
+	// the name must be unique
+	defer func() {
+		x := recover()
+		a.NotNil(x)
+		a.OK(strings.Contains(fmt.Sprint(x), `route name "user.show"`), x)
+	}()
+	router.Get("/people/:id", Named("user.show"))
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	a := assert.New(t)
+	router := NewRouter(nil)
+	router.Get("/users/:id", Named("user.show"), textHandler("user-"))
+	tmpl := template.Must(template.New("test").Funcs(TemplateFuncs(router)).Parse(`<a href="{{url "user.show" "id" .}}">`))
+	var buff bytes.Buffer
+	a.Nil(tmpl.Execute(&buff, 1))
+	a.EqStr(`<a href="/users/1">`, buff.String())
+
+	tmpl = template.Must(template.New("test").Funcs(TemplateFuncs(router)).Parse(`<a href="{{url "user.show"}}">`))
+	a.NotNil(tmpl.Execute(&buff, nil))
+}
@@ -16,6 +16,11 @@ func (w *wrapped) Error() string {
 	return fmt.Sprintf("%s: %s", w.message, w.cause)
 }
 
+// Unwrap returns the cause so that errors.Is and errors.As can inspect wrapped errors.
+func (w *wrapped) Unwrap() error {
+	return w.cause
+}
+
 // Wrap wraps an error with an additional message.
 func Wrap(err error, format string, args ...interface{}) error {
 	return &wrapped{
@@ -0,0 +1,105 @@
+package xtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is an interface to abstract the current time and timers so that time dependent logic can be tested.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock implementation by the system time. Now() respects RunAt.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FakeClock is a Clock implementation for testing. The time only moves by Advance.
+// If AutoAdvance is true, After advances the clock immediately so that callers never block.
+type FakeClock struct {
+	AutoAdvance bool
+
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	slept   []time.Duration
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a new *FakeClock at `t`
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{
+		now: t,
+	}
+}
+
+// Now implements Clock#Now
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implements Clock#After
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.slept = append(c.slept, d)
+	w := &fakeWaiter{
+		at: c.now.Add(d),
+		c:  make(chan time.Time, 1),
+	}
+	c.waiters = append(c.waiters, w)
+	c.mu.Unlock()
+	if c.AutoAdvance || d <= 0 {
+		c.Advance(d)
+	}
+	return w.c
+}
+
+// Advance moves the clock forward by d and fires the timers reached.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	var waiters []*fakeWaiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+		} else {
+			w.c <- c.now
+		}
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns the number of timers waiting for Advance.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Slept returns the list of durations requested by After.
+func (c *FakeClock) Slept() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	slept := make([]time.Duration, len(c.slept))
+	copy(slept, c.slept)
+	return slept
+}
@@ -0,0 +1,30 @@
+package xtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yssk22/go/x/xtesting/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	a := assert.New(t)
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(base)
+	ch := c.After(time.Second)
+	a.EqInt(1, c.Waiters())
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("timer should not fire yet")
+	default:
+	}
+	c.Advance(500 * time.Millisecond)
+	a.EqTime(base.Add(time.Second), <-ch)
+	a.EqInt(0, c.Waiters())
+
+	c.AutoAdvance = true
+	<-c.After(time.Minute)
+	a.EqTime(base.Add(time.Second+time.Minute), c.Now())
+	a.EqInt(2, len(c.Slept()))
+}